@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+// keysGenerate generates a signing key and writes it together with its JWK set
+func keysGenerate(cli *cli, args []string) error {
+	flags := cli.flags("keys generate", "")
+	alg := flags.String("alg", "RS256", "signing algorithm (RS256, ES256, ES384, ES512 or EdDSA)")
+	keyFile := flags.String("key", "", "path of the PEM private key to write (required)")
+	jwksFile := flags.String("jwks", "", "path of the JWK set to write, printed to stdout if empty")
+	passphraseFile := flags.String("passphrase-file", "", "encrypt the private key as PKCS#8 with the passphrase in this file")
+	force := flags.Bool("force", false, "overwrite existing files")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		flags.Usage()
+		return errInvalidUsage
+	}
+	passphrase, err := readPassphrase(*passphraseFile)
+	if err != nil {
+		return err
+	}
+
+	key, err := auth.GenerateSigningKey(*alg)
+	if err != nil {
+		return err
+	}
+	var keyPEM []byte
+	if len(passphrase) > 0 {
+		keyPEM, err = auth.PrivateKeyToPKCS8PEM(key, passphrase)
+	} else {
+		keyPEM, err = auth.PrivateKeyToPEM(key)
+	}
+	if err != nil {
+		return err
+	}
+	jwksJSON, err := indentedJwks(key.Public())
+	if err != nil {
+		return err
+	}
+	// neither file is written if the other one exists
+	if err := checkNotExists(*force, *keyFile, *jwksFile); err != nil {
+		return err
+	}
+	if err := writeFile(*keyFile, keyPEM, 0o600, *force); err != nil {
+		return err
+	}
+	if *jwksFile == "" {
+		_, err := cli.stdout.Write(jwksJSON)
+		return err
+	}
+	return writeFile(*jwksFile, jwksJSON, 0o644, *force)
+}
+
+// keysJwks converts a PEM private key, public key or certificate chain to a JWK set
+func keysJwks(cli *cli, args []string) error {
+	flags := cli.flags("keys jwks", "")
+	keyFile := flags.String("key", "", "path of the PEM private key, public key or certificate chain (required)")
+	passphraseFile := flags.String("passphrase-file", "", "passphrase of an encrypted PKCS#8 private key")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		flags.Usage()
+		return errInvalidUsage
+	}
+	data, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	var jwksJSON []byte
+	if certs, err := auth.ParseCertificatesFromPEMData(data); err == nil {
+		// certificates are exported with their chain and thumbprints
+		jwkSet, err := auth.JwkSetFromCertificateChains(certs)
+		if err != nil {
+			return err
+		}
+		if jwksJSON, err = json.MarshalIndent(jwkSet, "", "  "); err != nil {
+			return err
+		}
+		jwksJSON = append(jwksJSON, '\n')
+	} else {
+		pub, err := parsePublicKey(data, *passphraseFile)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %v", *keyFile, err)
+		}
+		if jwksJSON, err = indentedJwks(pub); err != nil {
+			return err
+		}
+	}
+	_, err = cli.stdout.Write(jwksJSON)
+	return err
+}
+
+// parsePublicKey parses a PEM public key or the public part of a private key
+func parsePublicKey(data []byte, passphraseFile string) (crypto.PublicKey, error) {
+	if pub, err := auth.ParsePublicKeyFromPEMData(data); err == nil {
+		return pub, nil
+	}
+	passphrase, err := readPassphrase(passphraseFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := auth.ParseEncryptedSigningKeyFromPEMData(data, passphrase)
+	if errors.Is(err, auth.ErrKeyPassphraseRequired) {
+		return nil, fmt.Errorf("%v, use -passphrase-file", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key.Public(), nil
+}
+
+// parseSigningKey parses a PEM private key file
+func parseSigningKey(keyFile, passphraseFile string) (crypto.Signer, error) {
+	passphrase, err := readPassphrase(passphraseFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := auth.ParseEncryptedSigningKeyFromPEMFile(keyFile, passphrase)
+	if errors.Is(err, auth.ErrKeyPassphraseRequired) {
+		return nil, fmt.Errorf("%v, use -passphrase-file", err)
+	}
+	return key, err
+}
+
+func readPassphrase(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return auth.LoadPassphraseFromFile(path)
+}
+
+// indentedJwks converts a public key to an indented JSON JWK set
+func indentedJwks(pub crypto.PublicKey) ([]byte, error) {
+	jwksJSON, err := auth.ToJwksJSON(pub)
+	if err != nil {
+		return nil, err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, jwksJSON, "", "  "); err != nil {
+		return nil, err
+	}
+	indented.WriteByte('\n')
+	return indented.Bytes(), nil
+}
+
+func errExists(path string) error {
+	return fmt.Errorf("%s already exists, use -force to overwrite it", path)
+}
+
+// checkNotExists fails if any of the paths exists unless force allows to overwrite existing files
+func checkNotExists(force bool, paths ...string) error {
+	if force {
+		return nil
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		_, err := os.Stat(path)
+		if err == nil {
+			return errExists(path)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes data to a new file unless force allows to overwrite an existing file
+//
+// The permissions of an overwritten file are set to perm before data is written.
+func writeFile(path string, data []byte, perm os.FileMode, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, perm)
+	if errors.Is(err, os.ErrExist) {
+		return errExists(path)
+	}
+	if err != nil {
+		return err
+	}
+	// the mode of an existing file is kept by os.OpenFile
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command runs a subcommand with its arguments
+type command func(cli *cli, args []string) error
+
+var commands = map[string]map[string]command{
+	"keys": {
+		"generate": keysGenerate,
+		"jwks":     keysJwks,
+	},
+	"token": {
+		"sign":   tokenSign,
+		"verify": tokenVerify,
+		"decode": tokenDecode,
+	},
+}
+
+// cli holds the standard streams of the command line interface
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// errInvalidUsage means the command was called with invalid arguments and the usage has been printed
+var errInvalidUsage = errors.New("invalid usage")
+
+func main() {
+	cli := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	switch err := cli.run(os.Args[1:]); {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errInvalidUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the subcommand given by args, e.g. `keys generate -alg ES256`
+func (cli *cli) run(args []string) error {
+	if len(args) < 2 {
+		cli.usage()
+		return errInvalidUsage
+	}
+	group, ok := commands[args[0]]
+	if !ok {
+		cli.usage()
+		return errInvalidUsage
+	}
+	cmd, ok := group[args[1]]
+	if !ok {
+		cli.usage()
+		return errInvalidUsage
+	}
+	return cmd(cli, args[2:])
+}
+
+func (cli *cli) usage() {
+	var names []string
+	for group, cmds := range commands {
+		for name := range cmds {
+			names = append(names, group+" "+name)
+		}
+	}
+	sort.Strings(names)
+	fmt.Fprintf(cli.stderr, "usage: go-service <command> [flags]\n\ncommands:\n  %s\n", strings.Join(names, "\n  "))
+}
+
+// flags creates a flag set for a subcommand that writes its usage to stderr
+func (cli *cli) flags(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(cli.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(cli.stderr, "usage: go-service %s [flags] %s\n\nflags:\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse parses the flags of a subcommand
+func parse(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errInvalidUsage
+	}
+	return nil
+}
+
+// input reads the positional argument or stdin if it is missing or `-`
+func (cli *cli) input(flags *flag.FlagSet) (string, error) {
+	if arg := flags.Arg(0); arg != "" && arg != "-" {
+		return arg, nil
+	}
+	data, err := ioutil.ReadAll(cli.stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// splitList splits a comma separated flag value
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
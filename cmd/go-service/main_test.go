@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+// runCLI runs the command line interface and returns stdout and stderr
+func runCLI(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	cli := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	err := cli.run(args)
+	return stdout.String(), stderr.String(), err
+}
+
+func TestKeysAndTokens(t *testing.T) {
+	t.Parallel()
+	for _, alg := range []string{"RS256", "ES256", "EdDSA"} {
+		dir := t.TempDir()
+		keyFile := filepath.Join(dir, "key.pem")
+		jwksFile := filepath.Join(dir, "jwks.json")
+		if _, _, err := runCLI(t, "", "keys", "generate", "-alg", alg, "-key", keyFile, "-jwks", jwksFile); err != nil {
+			t.Fatalf("failed to generate %s key: %v", alg, err)
+		}
+		if _, _, err := runCLI(t, "", "keys", "generate", "-alg", alg, "-key", keyFile); err == nil {
+			t.Errorf("expected existing key file not to be overwritten")
+		}
+
+		// the JWK set derived from the key matches the generated one
+		jwksJSON, _, err := runCLI(t, "", "keys", "jwks", "-key", keyFile)
+		if err != nil {
+			t.Fatalf("failed to convert %s key: %v", alg, err)
+		}
+		generated, err := os.ReadFile(jwksFile)
+		if err != nil {
+			t.Fatalf("failed to read JWK set: %v", err)
+		}
+		if jwksJSON != string(generated) {
+			t.Errorf("expected JWK set %s but got %s", generated, jwksJSON)
+		}
+
+		claims := `{"sub": "user", "role": "admin"}`
+		token, _, err := runCLI(t, claims, "token", "sign", "-key", keyFile, "-claims", "-", "-iss", "issuer", "-aud", "service")
+		if err != nil {
+			t.Fatalf("failed to sign %s token: %v", alg, err)
+		}
+		verified, _, err := runCLI(t, token, "token", "verify", "-jwks", jwksFile, "-iss", "issuer", "-aud", "service")
+		if err != nil {
+			t.Fatalf("failed to verify %s token: %v", alg, err)
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(verified), &payload); err != nil {
+			t.Fatalf("failed to parse claims %q: %v", verified, err)
+		}
+		if payload["sub"] != "user" || payload["role"] != "admin" || payload["iss"] != "issuer" {
+			t.Errorf("expected registered and custom claims but got %v", payload)
+		}
+
+		decoded, _, err := runCLI(t, "", "token", "decode", strings.TrimSpace(token))
+		if err != nil {
+			t.Fatalf("failed to decode %s token: %v", alg, err)
+		}
+		if !strings.Contains(decoded, `"alg": "`+alg+`"`) {
+			t.Errorf("expected decoded header with algorithm %s but got %s", alg, decoded)
+		}
+	}
+}
+
+func TestTokenVerifyReasons(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	jwksFile := filepath.Join(dir, "jwks.json")
+	otherJwksFile := filepath.Join(dir, "other.json")
+	for _, args := range [][]string{
+		{"-key", keyFile, "-jwks", jwksFile},
+		{"-key", filepath.Join(dir, "other.pem"), "-jwks", otherJwksFile},
+	} {
+		if _, _, err := runCLI(t, "", append([]string{"keys", "generate", "-alg", "ES256"}, args...)...); err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+	}
+	expired, _, err := runCLI(t, `{"exp": 1000000000}`, "token", "sign", "-key", keyFile, "-claims", "-")
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	valid, _, err := runCLI(t, "", "token", "sign", "-key", keyFile, "-iss", "issuer")
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	cases := []struct {
+		token  string
+		args   []string
+		reason string
+	}{
+		{expired, []string{"-jwks", jwksFile}, "token expired at 2001-09-09T01:46:40Z"},
+		{valid, []string{"-jwks", otherJwksFile}, "but the JWK set only contains"},
+		{valid, []string{"-jwks", jwksFile, "-iss", "other"}, `issuer "issuer" is not one of ["other"]`},
+		{"not-a-token", []string{"-jwks", jwksFile}, "token is malformed"},
+	}
+	for _, c := range cases {
+		_, _, err := runCLI(t, c.token, append([]string{"token", "verify"}, c.args...)...)
+		if err == nil || !strings.Contains(err.Error(), c.reason) {
+			t.Errorf("expected verification to fail with %q but got %v", c.reason, err)
+		}
+	}
+}
+
+func TestKeysJwksFromEncryptedKey(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	passphraseFile := filepath.Join(dir, "passphrase.txt")
+	if err := os.WriteFile(passphraseFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatalf("failed to write passphrase: %v", err)
+	}
+	jwksJSON, _, err := runCLI(t, "", "keys", "generate", "-alg", "ES384", "-key", keyFile, "-passphrase-file", passphraseFile)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if _, err := auth.ParseSigningKeyFromPEMFile(keyFile); !errors.Is(err, auth.ErrKeyPassphraseRequired) {
+		t.Errorf("expected encrypted key but got %v", err)
+	}
+	if _, _, err := runCLI(t, "", "keys", "jwks", "-key", keyFile); err == nil || !strings.Contains(err.Error(), "-passphrase-file") {
+		t.Errorf("expected missing passphrase to be reported but got %v", err)
+	}
+	converted, _, err := runCLI(t, "", "keys", "jwks", "-key", keyFile, "-passphrase-file", passphraseFile)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	if converted != jwksJSON {
+		t.Errorf("expected JWK set %s but got %s", jwksJSON, converted)
+	}
+}
+
+func TestKeysGenerateDoesNotOverwrite(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	jwksFile := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(jwksFile, []byte("{}"), 0o644); err != nil {
+		t.Fatalf("failed to write JWK set: %v", err)
+	}
+	if _, _, err := runCLI(t, "", "keys", "generate", "-key", keyFile, "-jwks", jwksFile); err == nil {
+		t.Errorf("expected existing JWK set not to be overwritten")
+	}
+	if _, err := os.Stat(keyFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected key not to be written if the JWK set exists, got %v", err)
+	}
+
+	// overwritten keys are only readable by the owner
+	if err := os.WriteFile(keyFile, nil, 0o644); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	if _, _, err := runCLI(t, "", "keys", "generate", "-key", keyFile, "-jwks", jwksFile, "-force"); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatalf("failed to stat key: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("expected key file mode %v but got %v", os.FileMode(0o600), info.Mode().Perm())
+	}
+}
+
+func TestInvalidUsage(t *testing.T) {
+	t.Parallel()
+	for _, args := range [][]string{
+		{},
+		{"keys"},
+		{"keys", "unknown"},
+		{"keys", "generate"},
+		{"token", "verify", "-unknown"},
+	} {
+		if _, stderr, err := runCLI(t, "", args...); !errors.Is(err, errInvalidUsage) || !strings.Contains(stderr, "usage:") {
+			t.Errorf("expected usage for %q but got %v", args, err)
+		}
+	}
+}
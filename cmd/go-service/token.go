@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+// registeredClaimNames are the JSON names of the fields of jwt.RegisteredClaims
+var registeredClaimNames = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// jsonClaims are registered claims with arbitrary custom claims
+type jsonClaims struct {
+	jwt.RegisteredClaims
+	custom map[string]interface{}
+}
+
+// GetRegisteredClaims implements auth.Claims
+func (claims *jsonClaims) GetRegisteredClaims() *jwt.RegisteredClaims {
+	return &claims.RegisteredClaims
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (claims *jsonClaims) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &claims.RegisteredClaims); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &claims.custom); err != nil {
+		return err
+	}
+	for _, name := range registeredClaimNames {
+		delete(claims.custom, name)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (claims *jsonClaims) MarshalJSON() ([]byte, error) {
+	registered, err := json.Marshal(&claims.RegisteredClaims)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]interface{}, len(claims.custom)+len(registeredClaimNames))
+	if err := json.Unmarshal(registered, &merged); err != nil {
+		return nil, err
+	}
+	for name, value := range claims.custom {
+		merged[name] = value
+	}
+	return json.Marshal(merged)
+}
+
+// tokenSign signs the claims of a JSON object with a PEM private key
+func tokenSign(cli *cli, args []string) error {
+	flags := cli.flags("token sign", "")
+	keyFile := flags.String("key", "", "path of the PEM private key (required)")
+	passphraseFile := flags.String("passphrase-file", "", "passphrase of an encrypted PKCS#8 private key")
+	jwksFile := flags.String("jwks", "", "JWK set to take the key id from, defaults to the key thumbprint")
+	claimsFile := flags.String("claims", "", "path of a JSON object with the claims, or - for stdin")
+	issuer := flags.String("iss", "", "issuer if the claims do not set one")
+	audience := flags.String("aud", "", "comma separated audiences to add")
+	subject := flags.String("sub", "", "subject, overriding the claims")
+	ttl := flags.Duration("ttl", 0, "lifetime of the token, defaults to the exp claim or 1h")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		flags.Usage()
+		return errInvalidUsage
+	}
+	key, err := parseSigningKey(*keyFile, *passphraseFile)
+	if err != nil {
+		return err
+	}
+	claims, err := cli.readClaims(*claimsFile)
+	if err != nil {
+		return err
+	}
+	signer := &auth.Authenticator{
+		Issuer:       *issuer,
+		ExpiresAfter: time.Hour,
+		SignKey:      key,
+	}
+	if *jwksFile != "" {
+		if signer.JwkSet, err = auth.LoadJwkSetFromFile(*jwksFile); err != nil {
+			return err
+		}
+	}
+	opts := []auth.SignOption{auth.WithAudience(splitList(*audience)...), auth.WithSubject(*subject)}
+	if *ttl > 0 {
+		opts = append(opts, auth.WithTTL(*ttl))
+	}
+	token, err := signer.Sign(claims, opts...)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cli.stdout, token)
+	return err
+}
+
+// readClaims reads a JSON object of claims from a file or stdin
+func (cli *cli) readClaims(path string) (*jsonClaims, error) {
+	claims := &jsonClaims{}
+	var data []byte
+	var err error
+	switch path {
+	case "":
+		return claims, nil
+	case "-":
+		data, err = ioutil.ReadAll(cli.stdin)
+	default:
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read claims: %v", err)
+	}
+	if err := json.Unmarshal(data, claims); err != nil {
+		return nil, fmt.Errorf("claims must be a JSON object: %v", err)
+	}
+	return claims, nil
+}
+
+// tokenVerify verifies a token against a JWK set and prints its claims
+func tokenVerify(cli *cli, args []string) error {
+	flags := cli.flags("token verify", "[token]")
+	jwksFile := flags.String("jwks", "", "path of the JWK set with the verification keys (required)")
+	issuers := flags.String("iss", "", "comma separated issuers to accept")
+	audiences := flags.String("aud", "", "comma separated audiences of which the token must contain one")
+	leeway := flags.Duration("leeway", 0, "tolerated clock skew")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if *jwksFile == "" {
+		flags.Usage()
+		return errInvalidUsage
+	}
+	jwkSet, err := auth.LoadJwkSetFromFile(*jwksFile)
+	if err != nil {
+		return err
+	}
+	token, err := cli.input(flags)
+	if err != nil {
+		return err
+	}
+	verifier := &auth.Authenticator{
+		JwkSet: jwkSet,
+		Validation: auth.ValidationOptions{
+			Issuers:   splitList(*issuers),
+			Audiences: splitList(*audiences),
+			Leeway:    *leeway,
+		},
+	}
+	claims := &jsonClaims{}
+	if _, _, err := verifier.Validate(token, claims); err != nil {
+		return fmt.Errorf("token is invalid: %s", verificationFailure(verifier, token, err))
+	}
+	return cli.printJSON(claims)
+}
+
+// verificationFailure explains why the verification of a token failed
+func verificationFailure(verifier *auth.Authenticator, token string, err error) string {
+	header := map[string]interface{}{}
+	claims := &jsonClaims{}
+	if parsed, _, parseErr := jwt.NewParser().ParseUnverified(token, claims); parseErr == nil {
+		header = parsed.Header
+	}
+	reg := &claims.RegisteredClaims
+	switch {
+	case errors.Is(err, auth.ErrTokenMalformed):
+		return fmt.Sprintf("token is malformed (%v)", err)
+	case errors.Is(err, auth.ErrUnknownKeyID):
+		return fmt.Sprintf("token is signed by key %v, but the JWK set only contains %s", header["kid"], keyIDs(verifier))
+	case errors.Is(err, auth.ErrInvalidSigningMethod):
+		return fmt.Sprintf("token algorithm %v does not match key %v (%v)", header["alg"], header["kid"], err)
+	case errors.Is(err, auth.ErrInvalidSignature):
+		return fmt.Sprintf("signature does not match key %v, the token was modified or signed by another key with the same id", header["kid"])
+	case errors.Is(err, auth.ErrTokenExpired), errors.Is(err, auth.ErrTokenNotValidYet), errors.Is(err, auth.ErrTokenUsedBeforeIssued):
+		return timeFailure(reg, err)
+	case errors.Is(err, auth.ErrInvalidIssuer):
+		return fmt.Sprintf("issuer %q is not one of %q", reg.Issuer, verifier.Validation.Issuers)
+	case errors.Is(err, auth.ErrInvalidAudience):
+		return fmt.Sprintf("audience %q does not contain any of %q", []string(reg.Audience), verifier.Validation.Audiences)
+	default:
+		return err.Error()
+	}
+}
+
+// timeFailure explains why the exp, nbf or iat claims of a token are invalid
+func timeFailure(reg *jwt.RegisteredClaims, err error) string {
+	switch {
+	case errors.Is(err, auth.ErrTokenExpired) && reg.ExpiresAt != nil:
+		return fmt.Sprintf("token expired at %s (%s ago)", formatTime(reg.ExpiresAt), time.Since(reg.ExpiresAt.Time).Round(time.Second))
+	case errors.Is(err, auth.ErrTokenNotValidYet) && reg.NotBefore != nil:
+		return fmt.Sprintf("token is not valid before %s (in %s)", formatTime(reg.NotBefore), time.Until(reg.NotBefore.Time).Round(time.Second))
+	case errors.Is(err, auth.ErrTokenUsedBeforeIssued) && reg.IssuedAt != nil:
+		return fmt.Sprintf("token is issued in the future at %s, check the clocks", formatTime(reg.IssuedAt))
+	default:
+		return err.Error()
+	}
+}
+
+// keyIDs lists the key ids of the JWK set of verifier
+func keyIDs(verifier *auth.Authenticator) string {
+	var ids []string
+	for i := 0; i < verifier.JwkSet.Len(); i++ {
+		key, _ := verifier.JwkSet.Get(i)
+		ids = append(ids, fmt.Sprintf("%q", key.KeyID()))
+	}
+	if len(ids) == 0 {
+		return "no keys"
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ", ")
+}
+
+// formatTime formats a numeric date as RFC 3339 in UTC
+func formatTime(date *jwt.NumericDate) string {
+	return date.UTC().Format(time.RFC3339)
+}
+
+// tokenDecode prints the header and claims of a token without verifying it
+func tokenDecode(cli *cli, args []string) error {
+	flags := cli.flags("token decode", "[token]")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	token, err := cli.input(flags)
+	if err != nil {
+		return err
+	}
+	claims := jwt.MapClaims{}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	if err != nil {
+		return fmt.Errorf("token is malformed: %v", err)
+	}
+	return cli.printJSON(map[string]interface{}{
+		"header": parsed.Header,
+		"claims": claims,
+	})
+}
+
+// printJSON prints a value as indented JSON to stdout
+func (cli *cli) printJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(cli.stdout, "%s\n", data)
+	return err
+}
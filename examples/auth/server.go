@@ -9,11 +9,14 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"runtime"
 	"syscall"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	pb "github.com/romnn/go-service/examples/auth/gen"
 	"github.com/romnn/go-service/pkg/auth"
+	grpcauth "github.com/romnn/go-service/pkg/grpc/auth"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -24,6 +27,10 @@ import (
 type User struct {
 	Email          string
 	HashedPassword string
+	// TOTPSecret enables the second factor, store it encrypted in production
+	TOTPSecret string
+	// RecoveryCodes are the hashes of unused recovery codes
+	RecoveryCodes []string
 }
 
 // UserDatabase is a mock user database
@@ -62,13 +69,18 @@ func (db *userDatabase) GetUserByEmail(email string) (*User, error) {
 // AuthService ...
 type AuthService struct {
 	pb.UnimplementedAuthServer
-	Authenticator *auth.Authenticator
-	Database      UserDatabase
+	Authenticator  *auth.Authenticator
+	Passwords      *auth.PasswordHashingPool
+	PasswordPolicy *auth.PasswordPolicy
+	TOTP           *auth.TOTP
+	Database       UserDatabase
 }
 
 // Claims encode the JWT token claims
 type Claims struct {
 	UserEmail string `json:"user-email"`
+	// AuthMethods tell services whether the user logged in using a second factor
+	auth.AuthMethods
 	jwt.RegisteredClaims
 }
 
@@ -80,16 +92,40 @@ func (claims *Claims) GetRegisteredClaims() *jwt.RegisteredClaims {
 
 // Validate validates a token
 func (s *AuthService) Validate(ctx context.Context, in *pb.ValidationRequest) (*pb.ValidationResult, error) {
-	valid, token, err := s.Authenticator.Validate(in.GetToken(), &Claims{})
+	claims, err := auth.ValidateAs[*Claims](s.Authenticator, in.GetToken())
 	if err != nil {
 		log.Println(err)
-		return &pb.ValidationResult{Valid: false}, status.Error(codes.Internal, "Failed to validate token")
+		return &pb.ValidationResult{Valid: false}, grpcauth.StatusError(err)
 	}
-	if claims, ok := token.Claims.(*Claims); ok && valid {
-		log.Printf("valid authentication claims: %v", claims)
-		return &pb.ValidationResult{Valid: true}, nil
+	log.Printf("valid authentication claims: %v", claims)
+	return &pb.ValidationResult{Valid: true}, nil
+}
+
+// Register registers a new user and logs them in
+func (s *AuthService) Register(ctx context.Context, in *pb.RegisterRequest) (*pb.AuthToken, error) {
+	if in.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing email")
+	}
+	if err := s.PasswordPolicy.Validate(in.GetPassword()); err != nil {
+		// violations are returned as errdetails.BadRequest field violations
+		return nil, grpcauth.StatusError(err)
+	}
+	if _, err := s.Database.GetUserByEmail(in.GetEmail()); err == nil {
+		return nil, status.Error(codes.AlreadyExists, "user already exists")
+	}
+	hashed, err := s.Passwords.Hash(ctx, in.GetPassword())
+	if err != nil {
+		log.Println(err)
+		return nil, grpcauth.StatusError(err)
 	}
-	return &pb.ValidationResult{Valid: false}, nil
+	s.Database.AddUser(&User{Email: in.GetEmail(), HashedPassword: hashed})
+
+	pair, err := s.Authenticator.Login(ctx, &Claims{UserEmail: in.GetEmail()}, auth.WithSubject(in.GetEmail()))
+	if err != nil {
+		log.Println(err)
+		return nil, status.Error(codes.Internal, "error while signing token")
+	}
+	return authToken(in.GetEmail(), pair), nil
 }
 
 // Login logs in a user
@@ -99,25 +135,124 @@ func (s *AuthService) Login(ctx context.Context, in *pb.LoginRequest) (*pb.AuthT
 		log.Println(err)
 		return nil, status.Error(codes.NotFound, "no such user")
 	}
-	if !auth.CheckPasswordHash(in.GetPassword(), user.HashedPassword) {
+	ok, rehash, err := s.Passwords.Verify(ctx, in.GetPassword(), user.HashedPassword)
+	if err != nil {
+		log.Println(err)
+		// fails fast with ResourceExhausted when too many logins are in flight
+		return nil, grpcauth.StatusError(err)
+	}
+	if !ok {
 		return nil, status.Error(codes.Unauthenticated, "unauthorized")
 	}
+	if rehash {
+		// migrate the user to the current password hashing parameters
+		if hashed, err := s.Passwords.Hash(ctx, in.GetPassword()); err == nil {
+			updated := *user
+			updated.HashedPassword = hashed
+			s.Database.AddUser(&updated)
+		} else {
+			log.Println(err)
+		}
+	}
+
+	claims := &Claims{UserEmail: user.Email}
+	claims.AddAuthMethods(auth.AMRPassword)
+	if user.TOTPSecret != "" {
+		if err := s.verifySecondFactor(ctx, user.Email, in); err != nil {
+			log.Println(err)
+			return nil, grpcauth.StatusError(err)
+		}
+		// signing adds the mfa method as well
+		claims.AddAuthMethods(auth.AMROTP)
+	}
 
 	// authenticated
-	token, err := s.Authenticator.SignJwtClaims(&Claims{
-		UserEmail: user.Email,
-	})
+	pair, err := s.Authenticator.Login(ctx, claims, auth.WithSubject(user.Email))
 	if err != nil {
 		log.Println(err)
 		return nil, status.Error(codes.Internal, "error while signing token")
 	}
+	return authToken(user.Email, pair), nil
+}
+
+// verifySecondFactor checks the one-time password or uses up a recovery code of a user
+func (s *AuthService) verifySecondFactor(ctx context.Context, email string, in *pb.LoginRequest) error {
+	// reload the user to keep a migrated password hash
+	user, err := s.Database.GetUserByEmail(email)
+	if err != nil {
+		return err
+	}
+	if in.GetRecoveryCode() == "" {
+		return s.TOTP.Verify(ctx, user.Email, user.TOTPSecret, in.GetOtp())
+	}
+	remaining, err := auth.UseRecoveryCode(s.Passwords.WithContext(ctx), in.GetRecoveryCode(), user.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+	updated := *user
+	updated.RecoveryCodes = remaining
+	s.Database.AddUser(&updated)
+	return nil
+}
+
+// EnableTOTP enables TOTP as second factor of a user and returns the secret and recovery codes
+//
+// The user must enter a code of the authenticator app on their next login.
+func (s *AuthService) EnableTOTP(ctx context.Context, in *pb.EnableTOTPRequest) (*pb.TOTPEnrollment, error) {
+	user, err := s.Database.GetUserByEmail(in.GetEmail())
+	if err != nil {
+		log.Println(err)
+		return nil, status.Error(codes.NotFound, "no such user")
+	}
+	ok, _, err := s.Passwords.Verify(ctx, in.GetPassword(), user.HashedPassword)
+	if err != nil {
+		log.Println(err)
+		return nil, grpcauth.StatusError(err)
+	}
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Println(err)
+		return nil, status.Error(codes.Internal, "error while generating secret")
+	}
+	uri, err := s.TOTP.ProvisioningURI(user.Email, secret)
+	if err != nil {
+		log.Println(err)
+		return nil, status.Error(codes.Internal, "error while generating secret")
+	}
+	recoveryCodes, hashes, err := auth.GenerateRecoveryCodes(s.Passwords.WithContext(ctx), auth.DefaultRecoveryCodes)
+	if err != nil {
+		log.Println(err)
+		return nil, grpcauth.StatusError(err)
+	}
+	updated := *user
+	updated.TOTPSecret = secret
+	updated.RecoveryCodes = hashes
+	s.Database.AddUser(&updated)
+	return &pb.TOTPEnrollment{Secret: secret, ProvisioningUri: uri, RecoveryCodes: recoveryCodes}, nil
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token
+func (s *AuthService) Refresh(ctx context.Context, in *pb.RefreshRequest) (*pb.AuthToken, error) {
+	claims := &Claims{}
+	pair, err := s.Authenticator.Refresh(ctx, in.GetRefreshToken(), claims)
+	if err != nil {
+		log.Println(err)
+		return nil, grpcauth.StatusError(err)
+	}
+	return authToken(claims.UserEmail, pair), nil
+}
 
-	expirationTime := time.Now().Add(s.Authenticator.ExpiresAfter)
+func authToken(email string, pair *auth.TokenPair) *pb.AuthToken {
 	return &pb.AuthToken{
-		Token:   token,
-		Email:   user.Email,
-		Expires: timestamppb.New(expirationTime),
-	}, nil
+		Token:          pair.AccessToken,
+		Email:          email,
+		RefreshToken:   pair.RefreshToken,
+		Expires:        timestamppb.New(pair.AccessExpiresAt),
+		RefreshExpires: timestamppb.New(pair.RefreshExpiresAt),
+	}
 }
 
 func main() {
@@ -130,6 +265,8 @@ func main() {
 		ExpiresAfter: 100 * time.Second,
 		Issuer:       "issuer@example.org",
 		Audience:     "example.org",
+		// refresh tokens are kept in memory, use a persistent store in production
+		RefreshTokens: auth.NewMemoryRefreshTokenStore(),
 	}
 
 	keyConfig := auth.KeyConfig{Generate: true}
@@ -137,8 +274,20 @@ func main() {
 		log.Fatalf("failed to setup keys: %v", err)
 	}
 
+	// hash passwords within 250ms, at most one per CPU at a time
+	hasher, err := auth.CalibratePasswordHasher(auth.NewArgon2idHasher(), 250*time.Millisecond)
+	if err != nil {
+		log.Fatalf("failed to calibrate password hasher: %v", err)
+	}
+	passwords := auth.NewPasswordHashingPool(hasher, runtime.NumCPU(), 64)
+	prometheus.MustRegister(passwords)
+
 	service := AuthService{
-		Authenticator: &authenticator,
+		Authenticator:  &authenticator,
+		Passwords:      passwords,
+		PasswordPolicy: auth.DefaultPasswordPolicy(),
+		// used one-time passwords are kept in memory, use a shared store when running multiple replicas
+		TOTP: auth.NewTOTP("example.org"),
 		Database: &userDatabase{
 			users: make(map[string]*User),
 		},
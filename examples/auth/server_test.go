@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/golang-jwt/jwt/v4"
 	pb "github.com/romnn/go-service/examples/auth/gen"
 	"github.com/romnn/go-service/pkg/auth"
+	"github.com/romnn/go-service/pkg/auth/authtest"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -30,25 +34,29 @@ type test struct {
 	service *AuthService
 	server  *grpc.Server
 	client  pb.AuthClient
+	clock   *authtest.FakeClock
 }
 
 func (test *test) setup(t *testing.T) *test {
 	var err error
 	t.Parallel()
 
-	authenticator := auth.Authenticator{
-		ExpiresAfter: 100 * time.Second,
-		Issuer:       "mock-issuer",
-		Audience:     "mock-audience",
-	}
-
-	config := auth.KeyConfig{Generate: true}
-	if err := authenticator.SetupKeys(&config); err != nil {
-		t.Fatalf("failed to setup keys: %v", err)
-	}
+	// sign with a pre-generated key and control the time of validation
+	test.clock = authtest.NewFakeClock(time.Now())
+	authenticator := authtest.NewAuthenticator(test.clock)
+	authenticator.ExpiresAfter = 100 * time.Second
+	authenticator.RefreshTokens = auth.NewMemoryRefreshTokenStore()
+	totp := auth.NewTOTP("example.org")
+	totp.Clock = test.clock
 
 	test.service = &AuthService{
-		Authenticator: &authenticator,
+		Authenticator: authenticator,
+		Passwords: auth.NewPasswordHashingPool(
+			&auth.Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLength: 32, SaltLength: 16},
+			4, 16,
+		),
+		PasswordPolicy: auth.DefaultPasswordPolicy(),
+		TOTP:           totp,
 		Database: &userDatabase{
 			users: make(map[string]*User),
 		},
@@ -179,12 +187,167 @@ func TestValidatesExistingUser(t *testing.T) {
 	assertIsValidToken(t, test.client, &validationReq)
 
 	// the token is invalid after it expires
-	expired := time.Now().Add(test.service.Authenticator.ExpiresAfter).Add(10 * time.Second)
-	at(expired, func() {
-		assertIsInvalidToken(t, test.client, &validationReq)
+	test.clock.Advance(test.service.Authenticator.ExpiresAfter + 10*time.Second)
+	assertIsInvalidToken(t, test.client, &validationReq)
+}
+
+func TestRegisterEnforcesPasswordPolicy(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	_, err := test.client.Register(context.Background(), &pb.RegisterRequest{
+		Email:    "test@example.com",
+		Password: "short",
+	})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Fatalf("expected status code %v but got %v", codes.InvalidArgument, code)
+	}
+	details := status.Convert(err).Details()
+	if len(details) != 1 {
+		t.Fatalf("expected bad request details but got %v", details)
+	}
+	if badRequest, ok := details[0].(*errdetails.BadRequest); !ok || badRequest.GetFieldViolations()[0].GetField() != "password" {
+		t.Errorf("expected password field violation but got %v", details[0])
+	}
+
+	registered, err := test.client.Register(context.Background(), &pb.RegisterRequest{
+		Email:    "test@example.com",
+		Password: "correct horse battery staple",
+	})
+	if err != nil {
+		t.Fatalf("failed to register user: %v", err)
+	}
+	assertIsValidToken(t, test.client, &pb.ValidationRequest{Token: registered.Token})
+	assertSuccessfulLogin(t, test.client, &pb.LoginRequest{
+		Email:    "test@example.com",
+		Password: "correct horse battery staple",
 	})
 }
 
+func TestLoginUpgradesPasswordHash(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	// users with a legacy bcrypt hash
+	password := "secret"
+	user := User{
+		Email:          "test@example.com",
+		HashedPassword: auth.MustHashPassword(password),
+	}
+	test.service.Database.AddUser(&user)
+
+	assertSuccessfulLogin(t, test.client, &pb.LoginRequest{
+		Email:    user.Email,
+		Password: password,
+	})
+	upgraded, err := test.service.Database.GetUserByEmail(user.Email)
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if !strings.HasPrefix(upgraded.HashedPassword, "$argon2id$") {
+		t.Errorf("expected password hash to be upgraded to argon2id, got %q", upgraded.HashedPassword)
+	}
+
+	// the upgraded hash is used for the next login
+	assertSuccessfulLogin(t, test.client, &pb.LoginRequest{
+		Email:    user.Email,
+		Password: password,
+	})
+}
+
+func TestRefreshRotatesRefreshToken(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	password := "secret"
+	user := User{
+		Email:          "test@example.com",
+		HashedPassword: auth.MustHashPassword(password),
+	}
+	test.service.Database.AddUser(&user)
+
+	response, err := assertSuccessfulLogin(t, test.client, &pb.LoginRequest{
+		Email:    user.Email,
+		Password: password,
+	})
+	if err != nil {
+		t.Fatalf("failed to login valid user: %v", err)
+	}
+
+	refreshed, err := test.client.Refresh(context.Background(), &pb.RefreshRequest{RefreshToken: response.RefreshToken})
+	if err != nil {
+		t.Fatalf("failed to refresh token: %v", err)
+	}
+	if refreshed.Email != user.Email {
+		t.Errorf("expected email %q but got %q", user.Email, refreshed.Email)
+	}
+	assertIsValidToken(t, test.client, &pb.ValidationRequest{Token: refreshed.Token})
+
+	// the old refresh token cannot be used again
+	_, err = test.client.Refresh(context.Background(), &pb.RefreshRequest{RefreshToken: response.RefreshToken})
+	if code := status.Code(err); code != codes.Unauthenticated {
+		t.Errorf("expected status code %v but got %v", codes.Unauthenticated, code)
+	}
+}
+
+func TestLoginRequiresSecondFactor(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	password := "secret"
+	user := User{
+		Email:          "test@example.com",
+		HashedPassword: auth.MustHashPassword(password),
+	}
+	test.service.Database.AddUser(&user)
+
+	enrollment, err := test.client.EnableTOTP(context.Background(), &pb.EnableTOTPRequest{
+		Email:    user.Email,
+		Password: password,
+	})
+	if err != nil {
+		t.Fatalf("failed to enable TOTP: %v", err)
+	}
+	if !strings.HasPrefix(enrollment.ProvisioningUri, "otpauth://totp/") || len(enrollment.RecoveryCodes) != auth.DefaultRecoveryCodes {
+		t.Fatalf("unexpected enrollment: %v", enrollment)
+	}
+
+	// the password alone is no longer sufficient
+	assertLoginFails(t, test.client, &pb.LoginRequest{Email: user.Email, Password: password})
+	assertLoginFails(t, test.client, &pb.LoginRequest{Email: user.Email, Password: password, Otp: "000000"})
+
+	otp, err := test.service.TOTP.Code(enrollment.Secret, test.clock.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	response, err := assertSuccessfulLogin(t, test.client, &pb.LoginRequest{
+		Email:    user.Email,
+		Password: password,
+		Otp:      otp,
+	})
+	if err != nil {
+		t.Fatalf("failed to login with second factor: %v", err)
+	}
+	claims, err := auth.ValidateAs[*Claims](test.service.Authenticator, response.Token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if !claims.HasAuthMethod(auth.AMRMultiFactor) {
+		t.Errorf("expected multi-factor authentication but got amr %v", claims.AMR)
+	}
+
+	// one-time passwords cannot be replayed
+	_, err = test.client.Login(context.Background(), &pb.LoginRequest{Email: user.Email, Password: password, Otp: otp})
+	if code := status.Code(err); code != codes.Unauthenticated {
+		t.Errorf("expected status code %v but got %v", codes.Unauthenticated, code)
+	}
+
+	// recovery codes can be used once
+	recovery := &pb.LoginRequest{Email: user.Email, Password: password, RecoveryCode: enrollment.RecoveryCodes[0]}
+	assertSuccessfulLogin(t, test.client, recovery)
+	assertLoginFails(t, test.client, recovery)
+}
+
 func TestValidationFailsForBadToken(t *testing.T) {
 	test := new(test).setup(t)
 	defer test.teardown()
@@ -196,14 +359,6 @@ func TestValidationFailsForBadToken(t *testing.T) {
 	}
 }
 
-// Overrides time value for tests, restores afterwards
-// ref: https://github.com/dgrijalva/jwt-go/blob/master/example_test.go#L81
-func at(t time.Time, f func()) {
-	jwt.TimeFunc = func() time.Time { return t }
-	f()
-	jwt.TimeFunc = time.Now
-}
-
 func assertSuccessfulLogin(t *testing.T, client pb.AuthClient, login *pb.LoginRequest) (*pb.AuthToken, error) {
 	result, err := client.Login(context.Background(), login)
 	if err != nil {
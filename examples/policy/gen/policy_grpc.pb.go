@@ -0,0 +1,221 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.17.3
+// source: policy.proto
+
+package gen
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// PolicyClient is the client API for Policy service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PolicyClient interface {
+	// anyone can call this method
+	GetPublic(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Caller, error)
+	// only authenticated users can call this method
+	GetProtected(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Caller, error)
+	// only authenticated users with the admin role can call this method
+	GetAdmin(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Caller, error)
+	// methods without a policy use the default policy of the server
+	GetDefault(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Caller, error)
+}
+
+type policyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPolicyClient(cc grpc.ClientConnInterface) PolicyClient {
+	return &policyClient{cc}
+}
+
+func (c *policyClient) GetPublic(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Caller, error) {
+	out := new(Caller)
+	err := c.cc.Invoke(ctx, "/policy.Policy/GetPublic", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyClient) GetProtected(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Caller, error) {
+	out := new(Caller)
+	err := c.cc.Invoke(ctx, "/policy.Policy/GetProtected", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyClient) GetAdmin(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Caller, error) {
+	out := new(Caller)
+	err := c.cc.Invoke(ctx, "/policy.Policy/GetAdmin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *policyClient) GetDefault(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Caller, error) {
+	out := new(Caller)
+	err := c.cc.Invoke(ctx, "/policy.Policy/GetDefault", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PolicyServer is the server API for Policy service.
+// All implementations must embed UnimplementedPolicyServer
+// for forward compatibility
+type PolicyServer interface {
+	// anyone can call this method
+	GetPublic(context.Context, *Empty) (*Caller, error)
+	// only authenticated users can call this method
+	GetProtected(context.Context, *Empty) (*Caller, error)
+	// only authenticated users with the admin role can call this method
+	GetAdmin(context.Context, *Empty) (*Caller, error)
+	// methods without a policy use the default policy of the server
+	GetDefault(context.Context, *Empty) (*Caller, error)
+	mustEmbedUnimplementedPolicyServer()
+}
+
+// UnimplementedPolicyServer must be embedded to have forward compatible implementations.
+type UnimplementedPolicyServer struct {
+}
+
+func (UnimplementedPolicyServer) GetPublic(context.Context, *Empty) (*Caller, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPublic not implemented")
+}
+func (UnimplementedPolicyServer) GetProtected(context.Context, *Empty) (*Caller, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProtected not implemented")
+}
+func (UnimplementedPolicyServer) GetAdmin(context.Context, *Empty) (*Caller, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAdmin not implemented")
+}
+func (UnimplementedPolicyServer) GetDefault(context.Context, *Empty) (*Caller, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDefault not implemented")
+}
+func (UnimplementedPolicyServer) mustEmbedUnimplementedPolicyServer() {}
+
+// UnsafePolicyServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PolicyServer will
+// result in compilation errors.
+type UnsafePolicyServer interface {
+	mustEmbedUnimplementedPolicyServer()
+}
+
+func RegisterPolicyServer(s grpc.ServiceRegistrar, srv PolicyServer) {
+	s.RegisterService(&Policy_ServiceDesc, srv)
+}
+
+func _Policy_GetPublic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyServer).GetPublic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/policy.Policy/GetPublic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyServer).GetPublic(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Policy_GetProtected_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyServer).GetProtected(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/policy.Policy/GetProtected",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyServer).GetProtected(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Policy_GetAdmin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyServer).GetAdmin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/policy.Policy/GetAdmin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyServer).GetAdmin(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Policy_GetDefault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PolicyServer).GetDefault(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/policy.Policy/GetDefault",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PolicyServer).GetDefault(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Policy_ServiceDesc is the grpc.ServiceDesc for Policy service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Policy_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "policy.Policy",
+	HandlerType: (*PolicyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPublic",
+			Handler:    _Policy_GetPublic_Handler,
+		},
+		{
+			MethodName: "GetProtected",
+			Handler:    _Policy_GetProtected_Handler,
+		},
+		{
+			MethodName: "GetAdmin",
+			Handler:    _Policy_GetAdmin_Handler,
+		},
+		{
+			MethodName: "GetDefault",
+			Handler:    _Policy_GetDefault_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "policy.proto",
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	pb "github.com/romnn/go-service/examples/policy/gen"
+	"github.com/romnn/go-service/pkg/auth"
+	grpcauth "github.com/romnn/go-service/pkg/grpc/auth"
+	"github.com/romnn/go-service/pkg/grpc/reflect"
+	"google.golang.org/grpc"
+)
+
+// Claims encode the JWT token claims
+type Claims struct {
+	UserID string   `json:"user-id"`
+	Roles  []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// GetRegisteredClaims returns the standard claims that will be set automatically
+func (claims *Claims) GetRegisteredClaims() *jwt.RegisteredClaims {
+	return &claims.RegisteredClaims
+}
+
+// GetRoles returns the roles of the user
+func (claims *Claims) GetRoles() []string {
+	return claims.Roles
+}
+
+// PolicyService implements the policy service
+type PolicyService struct {
+	pb.UnimplementedPolicyServer
+}
+
+func (s *PolicyService) getCaller(ctx context.Context) (*pb.Caller, error) {
+	claims, ok := grpcauth.ClaimsFromContext(ctx)
+	if !ok {
+		return &pb.Caller{Authenticated: false}, nil
+	}
+	return &pb.Caller{
+		Authenticated: true,
+		UserId:        claims.(*Claims).UserID,
+	}, nil
+}
+
+// GetPublic returns the caller
+func (s *PolicyService) GetPublic(ctx context.Context, req *pb.Empty) (*pb.Caller, error) {
+	return s.getCaller(ctx)
+}
+
+// GetProtected returns the caller
+func (s *PolicyService) GetProtected(ctx context.Context, req *pb.Empty) (*pb.Caller, error) {
+	return s.getCaller(ctx)
+}
+
+// GetAdmin returns the caller
+func (s *PolicyService) GetAdmin(ctx context.Context, req *pb.Empty) (*pb.Caller, error) {
+	return s.getCaller(ctx)
+}
+
+// GetDefault returns the caller
+func (s *PolicyService) GetDefault(ctx context.Context, req *pb.Empty) (*pb.Caller, error) {
+	return s.getCaller(ctx)
+}
+
+func main() {
+	listener, err := net.Listen("tcp", ":8080")
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	authenticator := auth.Authenticator{
+		ExpiresAfter: 100 * time.Second,
+		Issuer:       "issuer@example.org",
+		Audience:     "example.org",
+	}
+
+	keyConfig := auth.KeyConfig{Generate: true}
+	if err := authenticator.SetupKeys(&keyConfig); err != nil {
+		log.Fatalf("failed to setup keys: %v", err)
+	}
+
+	service := PolicyService{}
+	registry := reflect.NewRegistry()
+	enforcer := grpcauth.PolicyEnforcer{
+		Registry:      registry,
+		Authenticator: &authenticator,
+		NewClaims:     func() auth.Claims { return &Claims{} },
+	}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(enforcer.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(enforcer.StreamServerInterceptor()),
+	)
+	pb.RegisterPolicyServer(server, &service)
+	registry.Load(server)
+
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-shutdown
+		log.Println("shutdown ...")
+		server.GracefulStop()
+		listener.Close()
+	}()
+
+	log.Printf("listening on: %v", listener.Addr())
+	if err := server.Serve(listener); err != nil {
+		log.Fatalf("failed to serve: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/romnn/go-service/examples/policy/gen"
+	"github.com/romnn/go-service/pkg/auth"
+	"github.com/romnn/go-service/pkg/auth/authtest"
+	grpcauth "github.com/romnn/go-service/pkg/grpc/auth"
+	"github.com/romnn/go-service/pkg/grpc/reflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	bufSize = 1024 * 1024
+)
+
+type DialerFunc = func(string, time.Duration) (net.Conn, error)
+
+func dailerFor(listener *bufconn.Listener) DialerFunc {
+	return func(string, time.Duration) (net.Conn, error) {
+		return listener.Dial()
+	}
+}
+
+type test struct {
+	conn          *grpc.ClientConn
+	authenticator *auth.Authenticator
+	server        *grpc.Server
+	client        pb.PolicyClient
+}
+
+func (test *test) setup(t *testing.T) *test {
+	var err error
+	t.Parallel()
+
+	test.authenticator = authtest.NewAuthenticator(auth.SystemClock)
+	test.authenticator.ExpiresAfter = 100 * time.Second
+
+	registry := reflect.NewRegistry()
+	enforcer := grpcauth.PolicyEnforcer{
+		Registry:      registry,
+		Authenticator: test.authenticator,
+		NewClaims:     func() auth.Claims { return &Claims{} },
+	}
+	test.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(enforcer.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(enforcer.StreamServerInterceptor()),
+	)
+	pb.RegisterPolicyServer(test.server, &PolicyService{})
+	if err := registry.Load(test.server); err != nil {
+		t.Fatalf("failed to load registry: %v", err)
+	}
+
+	listener := bufconn.Listen(bufSize)
+	go func() {
+		_ = test.server.Serve(listener)
+	}()
+
+	test.conn, err = grpc.Dial(
+		"bufnet",
+		grpc.WithDialer(dailerFor(listener)),
+		grpc.WithInsecure(),
+		grpc.WithTimeout(20*time.Second),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial grpc service: %v", err)
+	}
+
+	test.client = pb.NewPolicyClient(test.conn)
+	return test
+}
+
+func (test *test) teardown() {
+	if test.conn != nil {
+		_ = test.conn.Close()
+	}
+	if test.server != nil {
+		test.server.GracefulStop()
+	}
+}
+
+func (test *test) withToken(t *testing.T, claims *Claims) context.Context {
+	token, err := test.authenticator.SignJwtClaims(claims)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func assertCode(t *testing.T, err error, expected codes.Code) {
+	t.Helper()
+	if code := status.Code(err); code != expected {
+		t.Errorf("expected status code %v but got %v (%v)", expected, code, err)
+	}
+}
+
+func TestPublicMethodAllowsAnonymous(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	caller, err := test.client.GetPublic(context.Background(), &pb.Empty{})
+	if err != nil {
+		t.Fatalf("failed to call public method: %v", err)
+	}
+	if caller.Authenticated {
+		t.Error("expected anonymous caller")
+	}
+
+	ctx := test.withToken(t, &Claims{UserID: "123"})
+	caller, err = test.client.GetPublic(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatalf("failed to call public method: %v", err)
+	}
+	if !caller.Authenticated || caller.UserId != "123" {
+		t.Errorf("expected authenticated caller %q but got %v", "123", caller)
+	}
+
+	// tokens that are present must still be valid
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer invalid")
+	_, err = test.client.GetPublic(ctx, &pb.Empty{})
+	assertCode(t, err, codes.Unauthenticated)
+}
+
+func TestProtectedMethodRequiresToken(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	_, err := test.client.GetProtected(context.Background(), &pb.Empty{})
+	assertCode(t, err, codes.Unauthenticated)
+
+	ctx := test.withToken(t, &Claims{UserID: "123"})
+	caller, err := test.client.GetProtected(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatalf("failed to call protected method: %v", err)
+	}
+	if caller.UserId != "123" {
+		t.Errorf("expected caller %q but got %q", "123", caller.UserId)
+	}
+}
+
+func TestAdminMethodRequiresRole(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	_, err := test.client.GetAdmin(context.Background(), &pb.Empty{})
+	assertCode(t, err, codes.Unauthenticated)
+
+	ctx := test.withToken(t, &Claims{UserID: "123", Roles: []string{"user"}})
+	_, err = test.client.GetAdmin(ctx, &pb.Empty{})
+	assertCode(t, err, codes.PermissionDenied)
+
+	ctx = test.withToken(t, &Claims{UserID: "123", Roles: []string{"user", "admin"}})
+	if _, err = test.client.GetAdmin(ctx, &pb.Empty{}); err != nil {
+		t.Fatalf("failed to call admin method: %v", err)
+	}
+}
+
+func TestDefaultPolicyRequiresToken(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	_, err := test.client.GetDefault(context.Background(), &pb.Empty{})
+	assertCode(t, err, codes.Unauthenticated)
+}
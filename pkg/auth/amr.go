@@ -0,0 +1,57 @@
+package auth
+
+// Authentication method references of the `amr` claim (RFC 8176)
+const (
+	AMRPassword    = "pwd"
+	AMROTP         = "otp"
+	AMRMultiFactor = "mfa"
+)
+
+// AuthMethods is the `amr` claim with the methods used to authenticate the subject of a token (RFC 8176)
+//
+// Embed it into custom claims and add AMROTP once the second factor has been verified,
+// so that services can require a second factor for sensitive operations (step-up authentication).
+type AuthMethods struct {
+	AMR []string `json:"amr,omitempty"`
+}
+
+// AuthMethodsClaims are claims with an `amr` claim
+type AuthMethodsClaims interface {
+	Claims
+	GetAuthMethods() *AuthMethods
+}
+
+// GetAuthMethods returns the `amr` claim
+func (methods *AuthMethods) GetAuthMethods() *AuthMethods {
+	return methods
+}
+
+// AddAuthMethods adds authentication methods to the `amr` claim, e.g. AMRPassword and AMROTP
+func (methods *AuthMethods) AddAuthMethods(amr ...string) {
+	methods.AMR = appendUnique(methods.AMR, amr...)
+}
+
+// HasAuthMethod reports whether the subject authenticated using the given method
+func (methods *AuthMethods) HasAuthMethod(method string) bool {
+	return contains(methods.AMR, method)
+}
+
+// setAuthMethods removes duplicate authentication methods
+// and adds AMRMultiFactor if the subject authenticated using more than one method
+func setAuthMethods(claims Claims) {
+	withMethods, ok := claims.(AuthMethodsClaims)
+	if !ok {
+		return
+	}
+	methods := withMethods.GetAuthMethods()
+	methods.AMR = appendUnique(nil, methods.AMR...)
+	factors := 0
+	for _, method := range methods.AMR {
+		if method != AMRMultiFactor {
+			factors++
+		}
+	}
+	if factors > 1 {
+		methods.AddAuthMethods(AMRMultiFactor)
+	}
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAPIKeyNotFound is returned by an APIKeyStore if there is no key with a prefix
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey is an API key as persisted by an APIKeyStore
+//
+// Keys have the format `prefix_secret`. The prefix identifies the key
+// and only the SHA-256 hash of the secret is stored.
+type APIKey struct {
+	Prefix     string
+	Hash       []byte
+	Subject    string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+}
+
+// Expired reports whether the key has expired. Keys with a zero ExpiresAt never expire.
+func (key *APIKey) Expired(now time.Time) bool {
+	return !key.ExpiresAt.IsZero() && !now.Before(key.ExpiresAt)
+}
+
+// APIKeyStore persists API keys by their prefix
+type APIKeyStore interface {
+	// Create stores a new API key
+	Create(ctx context.Context, key *APIKey) error
+	// Get returns the API key with a prefix or ErrAPIKeyNotFound
+	Get(ctx context.Context, prefix string) (*APIKey, error)
+	// Touch records that the API key with a prefix was used
+	Touch(ctx context.Context, prefix string, usedAt time.Time) error
+	// Delete deletes the API key with a prefix
+	Delete(ctx context.Context, prefix string) error
+}
+
+// MemoryAPIKeyStore is an in-memory APIKeyStore
+type MemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]*APIKey
+}
+
+// NewMemoryAPIKeyStore creates a new in-memory API key store
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{keys: make(map[string]*APIKey)}
+}
+
+// Create stores a new API key
+func (store *MemoryAPIKeyStore) Create(ctx context.Context, key *APIKey) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.keys[key.Prefix]; ok {
+		return fmt.Errorf("api key with prefix %q already exists", key.Prefix)
+	}
+	stored := *key
+	store.keys[key.Prefix] = &stored
+	return nil
+}
+
+// Get returns the API key with a prefix
+func (store *MemoryAPIKeyStore) Get(ctx context.Context, prefix string) (*APIKey, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	stored, ok := store.keys[prefix]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	key := *stored
+	return &key, nil
+}
+
+// Touch records that the API key with a prefix was used
+func (store *MemoryAPIKeyStore) Touch(ctx context.Context, prefix string, usedAt time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	stored, ok := store.keys[prefix]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	if usedAt.After(stored.LastUsedAt) {
+		stored.LastUsedAt = usedAt
+	}
+	return nil
+}
+
+// Delete deletes the API key with a prefix
+func (store *MemoryAPIKeyStore) Delete(ctx context.Context, prefix string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, ok := store.keys[prefix]; !ok {
+		return ErrAPIKeyNotFound
+	}
+	delete(store.keys, prefix)
+	return nil
+}
+
+// APIKeys creates and authenticates API keys
+type APIKeys struct {
+	Store APIKeyStore
+	// Clock is used to expire keys and record their usage, defaults to the system clock
+	Clock Clock
+}
+
+func (keys *APIKeys) now() time.Time {
+	if keys.Clock == nil {
+		return time.Now()
+	}
+	return keys.Clock.Now()
+}
+
+func hashAPIKeySecret(secret string) []byte {
+	hash := sha256.Sum256([]byte(secret))
+	return hash[:]
+}
+
+// splitAPIKey splits an API key into its prefix and secret
+func splitAPIKey(key string) (string, string, bool) {
+	parts := strings.SplitN(key, "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// Create generates a new API key for a subject
+//
+// The returned key is shown to the user once, only its hash is stored.
+// A zero ttl creates a key that never expires.
+func (keys *APIKeys) Create(ctx context.Context, subject string, scopes []string, ttl time.Duration) (string, *APIKey, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	now := keys.now()
+	key := APIKey{
+		Prefix:    hex.EncodeToString(id),
+		Hash:      hashAPIKeySecret(secret),
+		Subject:   subject,
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		key.ExpiresAt = now.Add(ttl)
+	}
+	if err := keys.Store.Create(ctx, &key); err != nil {
+		return "", nil, err
+	}
+	return key.Prefix + "_" + secret, &key, nil
+}
+
+// Authenticate looks up an API key by its prefix and returns its principal
+//
+// Invalid keys are rejected with a *ValidationError with reason ErrInvalidAPIKey.
+func (keys *APIKeys) Authenticate(ctx context.Context, apiKey string) (*Principal, error) {
+	prefix, secret, ok := splitAPIKey(apiKey)
+	if !ok {
+		return nil, &ValidationError{Reason: ErrInvalidAPIKey, Err: errors.New("malformed api key")}
+	}
+	key, err := keys.Store.Get(ctx, prefix)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		// compare anyway so that unknown prefixes take as long as wrong secrets
+		subtle.ConstantTimeCompare(hashAPIKeySecret(secret), make([]byte, sha256.Size))
+		return nil, &ValidationError{Reason: ErrInvalidAPIKey}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %v", err)
+	}
+	if subtle.ConstantTimeCompare(hashAPIKeySecret(secret), key.Hash) != 1 {
+		return nil, &ValidationError{Reason: ErrInvalidAPIKey}
+	}
+	now := keys.now()
+	if key.Expired(now) {
+		return nil, &ValidationError{Reason: ErrInvalidAPIKey, Err: errors.New("api key is expired")}
+	}
+	if err := keys.Store.Touch(ctx, prefix, now); err != nil {
+		return nil, fmt.Errorf("failed to record api key usage: %v", err)
+	}
+	return &Principal{
+		Subject: key.Subject,
+		Scopes:  key.Scopes,
+		Method:  AuthMethodAPIKey,
+	}, nil
+}
+
+// Revoke deletes the API key with a prefix
+func (keys *APIKeys) Revoke(ctx context.Context, prefix string) error {
+	return keys.Store.Delete(ctx, prefix)
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryAPIKeyStore()
+	keys := &APIKeys{Store: store}
+	ctx := context.Background()
+
+	apiKey, created, err := keys.Create(ctx, "service-a", []string{"read"}, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	if !strings.HasPrefix(apiKey, created.Prefix+"_") {
+		t.Errorf("expected api key %q to start with prefix %q", apiKey, created.Prefix)
+	}
+	stored, err := store.Get(ctx, created.Prefix)
+	if err != nil {
+		t.Fatalf("failed to get api key: %v", err)
+	}
+	// only the hash of the secret is stored
+	if len(stored.Hash) != sha256.Size || !stored.LastUsedAt.IsZero() {
+		t.Errorf("unexpected stored api key %+v", stored)
+	}
+
+	principal, err := keys.Authenticate(ctx, apiKey)
+	if err != nil {
+		t.Fatalf("failed to authenticate api key: %v", err)
+	}
+	if principal.Subject != "service-a" || !principal.HasScope("read") || principal.Method != AuthMethodAPIKey {
+		t.Errorf("unexpected principal %+v", principal)
+	}
+	if stored, _ := store.Get(ctx, created.Prefix); stored.LastUsedAt.IsZero() {
+		t.Error("expected last used time to be recorded")
+	}
+
+	invalid := []string{"", "no-separator", created.Prefix + "_wrong-secret", "unknown_" + strings.SplitN(apiKey, "_", 2)[1]}
+	for _, key := range invalid {
+		if _, err := keys.Authenticate(ctx, key); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("%q: expected error %v but got %v", key, ErrInvalidAPIKey, err)
+		}
+	}
+
+	if err := keys.Revoke(ctx, created.Prefix); err != nil {
+		t.Fatalf("failed to revoke api key: %v", err)
+	}
+	if _, err := keys.Authenticate(ctx, apiKey); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected error %v but got %v", ErrInvalidAPIKey, err)
+	}
+}
+
+func TestAPIKeyExpires(t *testing.T) {
+	t.Parallel()
+	keys := &APIKeys{Store: NewMemoryAPIKeyStore()}
+	apiKey, _, err := keys.Create(context.Background(), "service-a", nil, time.Nanosecond)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := keys.Authenticate(context.Background(), apiKey); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected error %v but got %v", ErrInvalidAPIKey, err)
+	}
+}
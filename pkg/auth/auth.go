@@ -1,9 +1,12 @@
 package auth
 
 import (
-	"crypto/rsa"
+	"context"
+	"crypto"
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -16,8 +19,43 @@ type Authenticator struct {
 	Audience     string
 	ExpiresAfter time.Duration
 
-	SignKey *rsa.PrivateKey
+	SignKey crypto.Signer
 	JwkSet  jwk.Set
+
+	// Secret signs and verifies tokens using HMAC instead of the asymmetric keys.
+	// Key types are strictly separated: if set, only tokens signed with the algorithm of the secret are accepted,
+	// otherwise HMAC signed tokens are always rejected.
+	Secret *SharedSecret
+
+	// Encryption encrypts signed tokens so that their claims are confidential.
+	// Validate decrypts encrypted tokens before verifying them.
+	Encryption *TokenEncryption
+
+	// Keyring holds rotated signing keys and takes precedence over SignKey.
+	// It is created on the first call to RotateKey if not set.
+	Keyring *Keyring
+	// RemoteJwks is consulted for verification keys that are neither in the keyring nor the JWK set
+	RemoteJwks *RemoteJwkSet
+
+	// RefreshTokens stores the refresh tokens issued by Login
+	RefreshTokens RefreshTokenStore
+	// RefreshExpiresAfter is the lifetime of refresh tokens, defaults to DefaultRefreshExpiresAfter
+	RefreshExpiresAfter time.Duration
+
+	// Revocations is consulted by Validate to reject revoked tokens
+	Revocations RevocationStore
+
+	// Validation configures how Validate checks the claims of a token
+	Validation ValidationOptions
+
+	// Clock is used to sign and validate tokens, defaults to the system clock
+	Clock Clock
+
+	mu sync.RWMutex
+	// signedUntil is the latest expiration of a signed token
+	signedUntil time.Time
+	// retired holds verification keys replaced by a KeyFileWatcher during their grace period
+	retired []*keyringEntry
 }
 
 // Claims defines the interface that custom JWT claim types must implement
@@ -27,65 +65,396 @@ type Claims interface {
 }
 
 // Validate checks a token if it is valid (e.g. has not expired)
+//
+// Besides the signature, the registered claims are checked according to the validation options.
+// Unless configured otherwise, only tokens of the Issuer and for the Audience of the authenticator are accepted.
+// Afterwards, the Valid method of custom claims is called to check the remaining claims.
+// If a revocation store is configured, revoked tokens are rejected with ErrTokenRevoked.
+// Encrypted tokens are decrypted first if token encryption is configured.
+// Errors are of type *ValidationError, use errors.Is to check the reason (e.g. ErrTokenExpired).
 func (auth *Authenticator) Validate(tokenString string, claims Claims) (bool, *jwt.Token, error) {
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		kid, ok := t.Header["kid"].(string)
-		if !ok {
-			return nil, fmt.Errorf("expecting JWT header kid to be string, but got %T", t.Header["kid"])
+	return auth.ValidateContext(context.Background(), tokenString, claims)
+}
+
+// ValidateContext is like Validate, but uses ctx to fetch the remote JWK set for unknown key ids
+func (auth *Authenticator) ValidateContext(ctx context.Context, tokenString string, claims Claims) (bool, *jwt.Token, error) {
+	tokenString, err := auth.decrypt(tokenString)
+	if err != nil {
+		return false, nil, newValidationError(err)
+	}
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	token, err := parser.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return auth.verificationKey(ctx, t)
+	})
+	if err != nil {
+		return false, nil, newValidationError(err)
+	}
+	now := auth.now()
+	opts := auth.Validation.withDefaults(auth.Issuer, auth.Audience)
+	if err := opts.validate(tokenString, claims, now); err != nil {
+		return false, nil, newValidationError(err)
+	}
+	if err := auth.checkRevoked(ctx, claims, now); err != nil {
+		return false, nil, err
+	}
+	return token.Valid, token, nil
+}
+
+// verificationKey finds the key to verify the signature of a token
+func (auth *Authenticator) verificationKey(ctx context.Context, t *jwt.Token) (interface{}, error) {
+	if auth.Secret != nil {
+		return auth.Secret.verificationKey(t)
+	}
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		return nil, fmt.Errorf("%w: %v requires a shared secret", ErrInvalidSigningMethod, t.Method.Alg())
+	}
+	kid, ok := t.Header["kid"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: expecting JWT header kid to be string, but got %T", ErrUnknownKeyID, t.Header["kid"])
+	}
+
+	matchingKey, ok := auth.lookupKeyID(ctx, kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: unable to find key with id %q", ErrUnknownKeyID, kid)
+	}
+	return verificationKey(t, matchingKey)
+}
+
+// verificationKey returns the raw public key of a JWK after checking that
+// the signing method of the token matches the type of the key.
+//
+// This prevents algorithm confusion, e.g. a token signed with HS256 using the public RSA key as the secret.
+func verificationKey(t *jwt.Token, key jwk.Key) (interface{}, error) {
+	publicKey, err := jwk.PublicKeyOf(key)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := publicKey.Raw(&raw); err != nil {
+		return nil, err
+	}
+	expected, err := SigningMethodForKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSigningMethod, err)
+	}
+	alg := t.Method.Alg()
+	switch expected.(type) {
+	case *jwt.SigningMethodRSA:
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("%w: expected RSA signing method, but got %v", ErrInvalidSigningMethod, alg)
+		}
+	default:
+		if alg != expected.Alg() {
+			return nil, fmt.Errorf("%w: expected %v signing method, but got %v", ErrInvalidSigningMethod, expected.Alg(), alg)
+		}
+	}
+	if keyAlg := key.Algorithm(); keyAlg != "" && keyAlg != alg {
+		return nil, fmt.Errorf("%w: key with id %q must be used with %v, but got %v", ErrInvalidSigningMethod, key.KeyID(), keyAlg, alg)
+	}
+	return raw, nil
+}
+
+func (auth *Authenticator) keyring() *Keyring {
+	auth.mu.RLock()
+	defer auth.mu.RUnlock()
+	return auth.Keyring
+}
+
+// keys returns the signing key and JWK set, which are swapped by a KeyFileWatcher
+func (auth *Authenticator) keys() (crypto.Signer, jwk.Set) {
+	auth.mu.RLock()
+	defer auth.mu.RUnlock()
+	return auth.SignKey, auth.JwkSet
+}
+
+func (auth *Authenticator) lookupKeyID(ctx context.Context, kid string) (jwk.Key, bool) {
+	if keyring := auth.keyring(); keyring != nil {
+		if key, ok := keyring.lookupKeyID(kid, auth.now()); ok {
+			return key, true
 		}
+	}
+	if _, jwkSet := auth.keys(); jwkSet != nil {
+		if key, ok := jwkSet.LookupKeyID(kid); ok {
+			return key, true
+		}
+	}
+	if key, ok := auth.lookupRetiredKeyID(kid); ok {
+		return key, true
+	}
+	if auth.RemoteJwks != nil {
+		return auth.RemoteJwks.LookupKeyID(ctx, kid)
+	}
+	return nil, false
+}
 
-		alg, ok := t.Header["alg"].(string)
-		if !ok {
-			return nil, fmt.Errorf("expecting JWT header alg to be string, but got %T", t.Header["alg"])
+// SigningKey returns the current signing key
+func (auth *Authenticator) SigningKey() (*SigningKey, error) {
+	if keyring := auth.keyring(); keyring != nil {
+		if current := keyring.Current(); current != nil {
+			return current, nil
 		}
+	}
+	signKey, jwkSet := auth.keys()
+	if signKey == nil {
+		return nil, errors.New("missing signing key")
+	}
+	id, err := signKeyID(signKey, jwkSet)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: id, Key: signKey}, nil
+}
 
-		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("expected RSA signing method, but got %v", alg)
+// signKeyID returns the key id of a directly configured signing key
+//
+// If the JWK set contains the public key, its key id is used so that configured
+// and previously persisted JWK sets remain valid.
+// Otherwise, the key id is the RFC 7638 thumbprint of the public key.
+func signKeyID(key crypto.Signer, jwkSet jwk.Set) (string, error) {
+	if match, ok := findPublicKey(jwkSet, key.Public()); ok {
+		return match.KeyID(), nil
+	}
+	return Thumbprint(key.Public())
+}
+
+// findPublicKey finds the JWK with a key id for a public key
+func findPublicKey(jwkSet jwk.Set, pub crypto.PublicKey) (jwk.Key, bool) {
+	equal, ok := pub.(interface{ Equal(crypto.PublicKey) bool })
+	for i := 0; ok && jwkSet != nil && i < jwkSet.Len(); i++ {
+		candidate, _ := jwkSet.Get(i)
+		publicKey, err := jwk.PublicKeyOf(candidate)
+		if err != nil || candidate.KeyID() == "" {
+			continue
 		}
+		var raw interface{}
+		if err := publicKey.Raw(&raw); err == nil && equal.Equal(raw) {
+			return candidate, true
+		}
+	}
+	return nil, false
+}
 
-		if matchingKey, ok := auth.JwkSet.LookupKeyID(kid); ok {
-			var key rsa.PublicKey
-			err := matchingKey.Raw(&key)
-			return &key, err
+// VerificationKeys returns the public keys of the JWK set and the keyring
+func (auth *Authenticator) VerificationKeys() jwk.Set {
+	set := jwk.NewSet()
+	if keyring := auth.keyring(); keyring != nil {
+		keys := keyring.jwkSet(auth.now())
+		for i := 0; i < keys.Len(); i++ {
+			key, _ := keys.Get(i)
+			set.Add(key)
 		}
-		return nil, fmt.Errorf("unable to find key with id %q", kid)
-	})
+	}
+	_, jwkSet := auth.keys()
+	for _, keys := range []jwk.Set{jwkSet, auth.retiredKeys()} {
+		for i := 0; keys != nil && i < keys.Len(); i++ {
+			key, _ := keys.Get(i)
+			if _, exists := set.LookupKeyID(key.KeyID()); exists {
+				continue
+			}
+			if publicKey, err := jwk.PublicKeyOf(key); err == nil {
+				set.Add(publicKey)
+			}
+		}
+	}
+	return set
+}
+
+// SigningAlgorithms returns the sorted signing algorithms of all verification keys
+func (auth *Authenticator) SigningAlgorithms() []string {
+	keys := auth.VerificationKeys()
+	seen := make(map[string]bool)
+	var algs []string
+	for i := 0; i < keys.Len(); i++ {
+		key, _ := keys.Get(i)
+		alg := key.Algorithm()
+		if alg == "" {
+			var raw interface{}
+			if err := key.Raw(&raw); err != nil {
+				continue
+			}
+			method, err := SigningMethodForKey(raw)
+			if err != nil {
+				continue
+			}
+			alg = method.Alg()
+		}
+		if !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// RotateKey makes key the current signing key, identified by its RFC 7638 thumbprint.
+//
+// The previous signing key remains valid for verification until all tokens it signed have expired,
+// including tokens signed with a longer lifetime using WithTTL and the leeway of the validation options.
+// When the keyring is created, it takes over SignKey, whose public key is removed from the JWK set.
+func (auth *Authenticator) RotateKey(key crypto.Signer) error {
+	if auth.Secret != nil {
+		return errors.New("signing keys cannot be rotated when using a shared secret")
+	}
+	id, err := Thumbprint(key.Public())
 	if err != nil {
-		return false, nil, err
+		return err
 	}
-	return token.Valid, token, nil
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	now := auth.now()
+	if auth.Keyring == nil {
+		auth.Keyring = &Keyring{keys: make(map[string]*keyringEntry)}
+		if auth.SignKey != nil {
+			currentID, err := signKeyID(auth.SignKey, auth.JwkSet)
+			if err != nil {
+				return err
+			}
+			if err := auth.Keyring.rotate(currentID, auth.SignKey, time.Time{}, now); err != nil {
+				return err
+			}
+			// otherwise, the JWK set would keep the key valid after the keyring retired it
+			auth.JwkSet = withoutKeyID(auth.JwkSet, currentID)
+		}
+	}
+	return auth.Keyring.rotate(id, key, auth.retireAt(now), now)
+}
+
+// retireAt returns when a replaced verification key can be retired because all tokens it signed have expired
+//
+// The caller must hold the lock of the authenticator.
+func (auth *Authenticator) retireAt(now time.Time) time.Time {
+	retireAt := now.Add(auth.ExpiresAfter)
+	if auth.signedUntil.After(retireAt) {
+		retireAt = auth.signedUntil
+	}
+	return retireAt.Add(auth.Validation.Leeway)
+}
+
+// signed records the expiration of a signed token
+func (auth *Authenticator) signed(expiresAt *jwt.NumericDate) {
+	if expiresAt == nil {
+		return
+	}
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	if expiresAt.After(auth.signedUntil) {
+		auth.signedUntil = expiresAt.Time
+	}
+}
+
+// withoutKeyID returns a copy of a JWK set without the key with the given id
+func withoutKeyID(jwkSet jwk.Set, kid string) jwk.Set {
+	if jwkSet == nil {
+		return nil
+	}
+	set := jwk.NewSet()
+	for i := 0; i < jwkSet.Len(); i++ {
+		if key, _ := jwkSet.Get(i); key.KeyID() != kid {
+			set.Add(key)
+		}
+	}
+	return set
+}
+
+// RotateKeysEvery rotates the signing key periodically until the context is cancelled.
+//
+// New keys are created using generate or, if nil, with the algorithm of the current signing key.
+// Rotation errors are sent to the returned channel if it has capacity.
+func (auth *Authenticator) RotateKeysEvery(ctx context.Context, interval time.Duration, generate func() (crypto.Signer, error)) <-chan error {
+	errs := make(chan error, 1)
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer close(errs)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := auth.rotateGenerated(generate); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return errs
+}
+
+func (auth *Authenticator) rotateGenerated(generate func() (crypto.Signer, error)) error {
+	if generate == nil {
+		current, err := auth.SigningKey()
+		if err != nil {
+			return err
+		}
+		method, err := SigningMethodForKey(current.Key.Public())
+		if err != nil {
+			return err
+		}
+		generate = func() (crypto.Signer, error) {
+			return GenerateSigningKey(method.Alg())
+		}
+	}
+	key, err := generate()
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %v", err)
+	}
+	return auth.RotateKey(key)
 }
 
 // SetupKeys loads or generates keys from the config
+//
+// If a secret is configured, it is used instead of asymmetric keys, which must not be configured.
 func (auth *Authenticator) SetupKeys(config *KeyConfig) error {
-	var errs = make([]error, 4)
-	if config.Jwks != "" {
-		auth.JwkSet, errs[0] = ParseJwkSet([]byte(config.Jwks))
+	passphrase, err := config.passphrase()
+	if err != nil {
+		return err
 	}
-	if config.JwksFile != "" {
-		auth.JwkSet, errs[1] = LoadJwkSetFromFile(config.JwksFile)
+	if err := auth.setupEncryption(config, passphrase); err != nil {
+		return err
 	}
-	if config.Key != "" {
-		auth.SignKey, errs[2] = ParseSigningKeyFromPEMData([]byte(config.Key))
+	if config.hasSecret() {
+		return auth.setupSecret(config)
 	}
-	if config.KeyFile != "" {
-		auth.SignKey, errs[3] = ParseSigningKeyFromPEMFile(config.KeyFile)
+	if config.JwksURL != "" {
+		auth.RemoteJwks = NewRemoteJwkSet(context.Background(), config.JwksURL, nil)
 	}
-	for _, err := range errs {
+	if config.Generate && config.KeyFile != "" {
+		// generated keys are persisted and reused on later starts
+		signKey, jwkSet, err := loadOrGenerateKeyFiles(config.KeyFile, config.JwksFile, config.Algorithm, passphrase)
 		if err != nil {
 			return err
 		}
+		auth.SignKey, auth.JwkSet = signKey, jwkSet
+		return nil
+	}
+	signKey, jwkSet, err := loadKeys(config, passphrase)
+	if err != nil {
+		return err
+	}
+	if signKey != nil {
+		auth.SignKey = signKey
+	}
+	if jwkSet != nil {
+		auth.JwkSet = jwkSet
+	}
+	if auth.SignKey == nil && (auth.RemoteJwks != nil || config.hasCertificate()) {
+		// verification only
+		return nil
 	}
 	if auth.SignKey == nil || auth.JwkSet == nil {
 		if !config.Generate {
 			return errors.New("missing signing key or jwk set and --generate disabled")
 		}
-		keyPair, err := GenerateRSAKeyPair()
+		signKey, err := GenerateSigningKey(config.Algorithm)
 		if err != nil {
-			return fmt.Errorf("failed to generate RSA key pair: %v", err)
+			return fmt.Errorf("failed to generate signing key: %v", err)
 		}
-		auth.SignKey = keyPair.PrivateKey
-		jwkSet, err := ToJwks(keyPair.PublicKey)
+		auth.SignKey = signKey
+		jwkSet, err := ToJwks(signKey.Public())
 		if err != nil {
 			return fmt.Errorf("failed to generate JWK set: %v", err)
 		}
@@ -93,3 +462,90 @@ func (auth *Authenticator) SetupKeys(config *KeyConfig) error {
 	}
 	return nil
 }
+
+func (auth *Authenticator) setupEncryption(config *KeyConfig, passphrase []byte) error {
+	encryption, err := config.encryption(passphrase)
+	if err != nil {
+		return err
+	}
+	if encryption != nil {
+		auth.Encryption = encryption
+	}
+	return nil
+}
+
+func (auth *Authenticator) setupSecret(config *KeyConfig) error {
+	secret, err := config.secret()
+	if err != nil {
+		return err
+	}
+	auth.Secret = secret
+	return nil
+}
+
+// loadKeys loads the configured signing key and JWK set
+//
+// Files take precedence over inline keys. If no JWK set is configured, it is derived from the signing key.
+func loadKeys(config *KeyConfig, passphrase []byte) (signKey crypto.Signer, jwkSet jwk.Set, err error) {
+	switch {
+	case config.KeyFile != "":
+		signKey, err = ParseEncryptedSigningKeyFromPEMFile(config.KeyFile, passphrase)
+	case config.Key != "":
+		signKey, err = ParseEncryptedSigningKeyFromPEMData([]byte(config.Key), passphrase)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case config.JwksFile != "":
+		jwkSet, err = LoadJwkSetFromFile(config.JwksFile)
+	case config.Jwks != "":
+		jwkSet, err = ParseJwkSet([]byte(config.Jwks))
+	case signKey != nil:
+		jwkSet, err = ToJwks(signKey.Public())
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.hasCertificate() {
+		if jwkSet == nil {
+			jwkSet = jwk.NewSet()
+		}
+		if err := addCertificateJwk(jwkSet, config); err != nil {
+			return nil, nil, err
+		}
+	}
+	return signKey, jwkSet, nil
+}
+
+// addCertificateJwk adds the public key of the configured certificate chain to the JWK set
+//
+// Like signing keys, its key id is the RFC 7638 thumbprint of the public key so that services
+// verifying with the certificate accept tokens signed with the matching key.
+// A key with the same key id is replaced by the certificate JWK.
+func addCertificateJwk(jwkSet jwk.Set, config *KeyConfig) error {
+	chain, err := config.certificates()
+	if err != nil {
+		return err
+	}
+	id, err := Thumbprint(chain[0].PublicKey)
+	if err != nil {
+		return err
+	}
+	key, err := CertificateChainJwk(id, chain)
+	if err != nil {
+		return err
+	}
+	if existing, ok := jwkSet.LookupKeyID(id); ok {
+		jwkSet.Remove(existing)
+	}
+	jwkSet.Add(key)
+	return nil
+}
+
+// Close stops background refreshing of remote keys
+func (auth *Authenticator) Close() {
+	if auth.RemoteJwks != nil {
+		auth.RemoteJwks.Close()
+	}
+}
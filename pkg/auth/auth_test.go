@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/x509"
+	"errors"
 	"testing"
 	"time"
 
@@ -62,15 +64,14 @@ func TestValidatesValidToken(t *testing.T) {
 
 	// Check that the token no longer validates after it expires
 	expired := time.Now().Add(test.authenticator.ExpiresAfter).Add(10 * time.Second)
-	at(expired, func() {
-		valid, token, err := test.authenticator.Validate(tokenString, &testClaims{})
-		if err == nil {
-			t.Error("expected error when validating expired user token")
-		}
-		if valid || token != nil {
-			t.Errorf("unexpected valid=%t and token=%v on expired user token", valid, token)
-		}
-	})
+	test.authenticator.Clock = ClockFunc(func() time.Time { return expired })
+	valid, token, err = test.authenticator.Validate(tokenString, &testClaims{})
+	if err == nil {
+		t.Error("expected error when validating expired user token")
+	}
+	if valid || token != nil {
+		t.Errorf("unexpected valid=%t and token=%v on expired user token", valid, token)
+	}
 }
 
 func TestValidateInvalidTokenFails(t *testing.T) {
@@ -83,12 +84,254 @@ func TestValidateInvalidTokenFails(t *testing.T) {
 	if valid || token != nil {
 		t.Errorf("unexpected valid=%t and token=%v on invalid user token", valid, token)
 	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Reason != ErrTokenMalformed {
+		t.Errorf("expected validation error with reason %v but got %v", ErrTokenMalformed, err)
+	}
+}
+
+func TestValidateErrorReasons(t *testing.T) {
+	test := new(test).setup(t)
+
+	tokenString, err := test.authenticator.SignJwtClaims(&testClaims{UserID: "123"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	// tampered signature
+	tampered := tokenString[:len(tokenString)-4] + "AAAA"
+	if _, _, err := test.authenticator.Validate(tampered, &testClaims{}); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected error %v but got %v", ErrInvalidSignature, err)
+	}
+
+	// signed by a key that is not known
+	other := &Authenticator{ExpiresAfter: time.Minute}
+	if err := other.SetupKeys(&KeyConfig{Generate: true, Algorithm: "ES256"}); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	if other.Keyring, err = NewKeyring("other", other.SignKey); err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	foreign, err := other.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, _, err := test.authenticator.Validate(foreign, &testClaims{}); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected error %v but got %v", ErrUnknownKeyID, err)
+	}
+
+	// all reasons match ErrTokenInvalid
+	if _, _, err := test.authenticator.Validate(tampered, &testClaims{}); !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("expected error %v but got %v", ErrTokenInvalid, err)
+	}
+}
+
+func TestSigningAlgorithms(t *testing.T) {
+	t.Parallel()
+	for _, alg := range []string{"ES256", "ES384", "ES512", "EdDSA"} {
+		authenticator := &Authenticator{
+			ExpiresAfter: 100 * time.Second,
+			Issuer:       "mock-issuer",
+			Audience:     "mock-audience",
+		}
+		config := KeyConfig{Generate: true, Algorithm: alg}
+		if err := authenticator.SetupKeys(&config); err != nil {
+			t.Fatalf("failed to setup %s keys: %v", alg, err)
+		}
+		tokenString, err := authenticator.SignJwtClaims(&testClaims{UserID: "123"})
+		if err != nil {
+			t.Fatalf("failed to sign %s token: %v", alg, err)
+		}
+		valid, token, err := authenticator.Validate(tokenString, &testClaims{})
+		if err != nil || !valid {
+			t.Fatalf("failed to validate %s token: %v", alg, err)
+		}
+		if token.Method.Alg() != alg {
+			t.Errorf("expected token to be signed with %s but got %s", alg, token.Method.Alg())
+		}
+	}
+}
+
+func TestSigningKeyPEMRoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, alg := range []string{"ES256", "EdDSA"} {
+		key, err := GenerateSigningKey(alg)
+		if err != nil {
+			t.Fatalf("failed to generate %s key: %v", alg, err)
+		}
+		pemData, err := PrivateKeyToPEM(key)
+		if err != nil {
+			t.Fatalf("failed to encode %s key: %v", alg, err)
+		}
+		parsed, err := ParseSigningKeyFromPEMData(pemData)
+		if err != nil {
+			t.Fatalf("failed to parse %s key: %v", alg, err)
+		}
+		method, err := SigningMethodForKey(parsed.Public())
+		if err != nil || method.Alg() != alg {
+			t.Errorf("expected parsed key to use %s but got %v (%v)", alg, method, err)
+		}
+	}
+}
+
+func TestValidateRejectsAlgorithmConfusion(t *testing.T) {
+	test := new(test).setup(t)
+
+	// HS256 token using the public RSA key as the HMAC secret
+	publicKeyPEM, err := x509.MarshalPKIXPublicKey(test.authenticator.SignKey.Public())
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	signingKey, err := test.authenticator.SigningKey()
+	if err != nil {
+		t.Fatalf("failed to get signing key: %v", err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &testClaims{UserID: "123"})
+	token.Header["kid"] = signingKey.ID
+	hmacToken, err := token.SignedString(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	// ES256 token that claims to be signed by the RSA key
+	ecKey, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	token = jwt.NewWithClaims(jwt.SigningMethodES256, &testClaims{UserID: "123"})
+	token.Header["kid"] = signingKey.ID
+	ecToken, err := token.SignedString(ecKey)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	for _, tokenString := range []string{hmacToken, ecToken} {
+		valid, _, err := test.authenticator.Validate(tokenString, &testClaims{})
+		if err == nil || valid {
+			t.Errorf("expected validation of token %q to fail", tokenString)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	t.Parallel()
+	authenticator := &Authenticator{
+		ExpiresAfter: 100 * time.Second,
+		Issuer:       "mock-issuer",
+		Audience:     "mock-audience",
+	}
+	config := KeyConfig{Generate: true, Algorithm: "ES256"}
+	if err := authenticator.SetupKeys(&config); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	oldToken, err := authenticator.SignJwtClaims(&testClaims{UserID: "123"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	key, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := authenticator.RotateKey(key); err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+	newToken, err := authenticator.SignJwtClaims(&testClaims{UserID: "123"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	for _, tokenString := range []string{oldToken, newToken} {
+		if valid, _, err := authenticator.Validate(tokenString, &testClaims{}); err != nil || !valid {
+			t.Errorf("expected token %q to be valid after rotation: %v", tokenString, err)
+		}
+	}
+	_, token, _ := authenticator.Validate(newToken, &testClaims{})
+	if kid, _ := Thumbprint(key.Public()); token.Header["kid"] != kid {
+		t.Errorf("expected rotated key to have key id %q, but got %q", kid, token.Header["kid"])
+	}
+	if keys := authenticator.VerificationKeys(); keys.Len() != 2 {
+		t.Errorf("expected 2 verification keys but got %d", keys.Len())
+	}
+}
+
+func TestRotateKeyRetiresPreviousKeyAfterLongestToken(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	authenticator := &Authenticator{
+		ExpiresAfter: 100 * time.Second,
+		Validation:   ValidationOptions{Leeway: 10 * time.Second},
+		Clock:        ClockFunc(func() time.Time { return now }),
+	}
+	if err := authenticator.SetupKeys(&KeyConfig{Generate: true, Algorithm: "ES256"}); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	oldKey, err := authenticator.SigningKey()
+	if err != nil {
+		t.Fatalf("failed to get signing key: %v", err)
+	}
+	longToken, err := authenticator.Sign(&testClaims{}, WithTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	key, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := authenticator.RotateKey(key); err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+	if _, ok := authenticator.JwkSet.LookupKeyID(oldKey.ID); ok {
+		t.Errorf("expected key %q to be removed from the JWK set when taken over by the keyring", oldKey.ID)
+	}
+
+	now = now.Add(time.Hour + 5*time.Second)
+	if _, err := ValidateAs[*testClaims](authenticator, longToken); err != nil {
+		t.Errorf("expected token to be valid within the leeway after rotation: %v", err)
+	}
+	now = now.Add(10 * time.Second)
+	if _, ok := authenticator.VerificationKeys().LookupKeyID(oldKey.ID); ok {
+		t.Errorf("expected key %q to be retired after the longest token and leeway", oldKey.ID)
+	}
+	if _, err := ValidateAs[*testClaims](authenticator, longToken); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected error %v but got %v", ErrUnknownKeyID, err)
+	}
 }
 
-// Override time value for tests and restore after
-// ref: https://github.com/dgrijalva/jwt-go/blob/master/example_test.go#L81
-func at(t time.Time, f func()) {
-	jwt.TimeFunc = func() time.Time { return t }
-	f()
-	jwt.TimeFunc = time.Now
+func TestKeyringRetiresExpiredKeys(t *testing.T) {
+	t.Parallel()
+	oldKey, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	newKey, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyring, err := NewKeyring("old", oldKey)
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	if err := keyring.Rotate("new", newKey, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+	if current := keyring.Current(); current.ID != "new" {
+		t.Errorf("expected current key %q but got %q", "new", current.ID)
+	}
+	if _, ok := keyring.LookupKeyID("old"); ok {
+		t.Error("expected expired key to be removed")
+	}
+	if keys := keyring.JwkSet(); keys.Len() != 1 {
+		t.Errorf("expected 1 verification key but got %d", keys.Len())
+	}
+	if err := keyring.Rotate("new", newKey, time.Now()); err == nil {
+		t.Error("expected error when rotating to an existing key id")
+	}
+	if err := keyring.Rotate("newer", oldKey, time.Time{}); err == nil {
+		t.Error("expected error when rotating without retire time")
+	}
+	if current := keyring.Current(); current.ID != "new" {
+		t.Errorf("expected current key %q but got %q", "new", current.ID)
+	}
 }
@@ -0,0 +1,84 @@
+package authtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+const (
+	// Issuer is the issuer of test tokens
+	Issuer = "authtest-issuer"
+	// Audience is the audience of test tokens
+	Audience = "authtest-audience"
+	// ExpiresAfter is the lifetime of test tokens
+	ExpiresAfter = time.Hour
+)
+
+// Claims are the claims of test tokens
+type Claims struct {
+	jwt.RegisteredClaims
+}
+
+// GetRegisteredClaims returns the registered claims
+func (claims *Claims) GetRegisteredClaims() *jwt.RegisteredClaims {
+	return &claims.RegisteredClaims
+}
+
+// NewAuthenticator creates an authenticator that signs and validates tokens with Key using clock
+func NewAuthenticator(clock auth.Clock) *auth.Authenticator {
+	key := Key()
+	jwkSet, err := auth.ToJwks(key.Public())
+	if err != nil {
+		panic(err)
+	}
+	return &auth.Authenticator{
+		Issuer:       Issuer,
+		Audience:     Audience,
+		ExpiresAfter: ExpiresAfter,
+		SignKey:      key,
+		JwkSet:       jwkSet,
+		Clock:        clock,
+	}
+}
+
+// TokenFactory signs tokens for tests
+type TokenFactory struct {
+	Authenticator *auth.Authenticator
+	Clock         *FakeClock
+}
+
+// NewTokenFactory creates a token factory with a fake clock starting at the current time
+func NewTokenFactory() *TokenFactory {
+	clock := NewFakeClock(time.Now())
+	return &TokenFactory{
+		Authenticator: NewAuthenticator(clock),
+		Clock:         clock,
+	}
+}
+
+// Token signs claims and fails the test on error
+func (factory *TokenFactory) Token(t testing.TB, claims auth.Claims, opts ...auth.SignOption) string {
+	t.Helper()
+	token, err := factory.Authenticator.Sign(claims, opts...)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+// SubjectToken signs a token for subject
+func (factory *TokenFactory) SubjectToken(t testing.TB, subject string, opts ...auth.SignOption) string {
+	t.Helper()
+	opts = append([]auth.SignOption{auth.WithSubject(subject)}, opts...)
+	return factory.Token(t, &Claims{}, opts...)
+}
+
+// ExpiredToken signs claims with an expiration time that has already passed
+func (factory *TokenFactory) ExpiredToken(t testing.TB, claims auth.Claims, opts ...auth.SignOption) string {
+	t.Helper()
+	opts = append(opts, auth.WithTTL(-time.Minute))
+	return factory.Token(t, claims, opts...)
+}
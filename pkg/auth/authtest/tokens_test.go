@@ -0,0 +1,49 @@
+package authtest
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+func TestTokenFactory(t *testing.T) {
+	t.Parallel()
+	factory := NewTokenFactory()
+	token := factory.SubjectToken(t, "user-1")
+
+	claims, err := auth.ValidateAs[*Claims](factory.Authenticator, token)
+	if err != nil {
+		t.Fatalf("expected token to be valid: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Issuer != Issuer {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	// the token expires when the fake clock passes its expiration time
+	factory.Clock.Advance(ExpiresAfter + time.Second)
+	if _, err := auth.ValidateAs[*Claims](factory.Authenticator, token); !errors.Is(err, auth.ErrTokenExpired) {
+		t.Errorf("expected error %v but got %v", auth.ErrTokenExpired, err)
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	t.Parallel()
+	factory := NewTokenFactory()
+	token := factory.ExpiredToken(t, &Claims{})
+	if _, err := auth.ValidateAs[*Claims](factory.Authenticator, token); !errors.Is(err, auth.ErrTokenExpired) {
+		t.Errorf("expected error %v but got %v", auth.ErrTokenExpired, err)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	t.Parallel()
+	for alg, key := range map[string]crypto.Signer{"ES256": Key(), "RS256": RSAKey()} {
+		method, err := auth.SigningMethodForKey(key.Public())
+		if err != nil || method.Alg() != alg {
+			t.Errorf("expected key to sign with %s but got %v (%v)", alg, method, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// maxArgon2idTime bounds the argon2id time parameter during calibration
+	maxArgon2idTime = 64
+	// maxScryptLogN bounds the scrypt cost during calibration, which uses 128 * r * 2^LogN bytes of memory
+	maxScryptLogN = 20
+)
+
+func measure(hasher PasswordHasher) (time.Duration, error) {
+	start := time.Now()
+	_, err := hasher.Hash("calibration password")
+	return time.Since(start), err
+}
+
+// calibrate measures hashers of increasing cost and returns the strongest one
+// that hashes within target. The base hasher at i=0 is returned if all others are too slow.
+func calibrate(target time.Duration, next func(i int) (PasswordHasher, bool)) (PasswordHasher, error) {
+	best, _ := next(0)
+	for i := 1; ; i++ {
+		candidate, ok := next(i)
+		if !ok {
+			return best, nil
+		}
+		elapsed, err := measure(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if elapsed > target {
+			return best, nil
+		}
+		best = candidate
+	}
+}
+
+// CalibratePasswordHasher raises the cost of a built-in password hasher
+// to the strongest parameters that hash a password within the target latency
+//
+// Calibration takes a few multiples of target and should run once at startup.
+// The returned hasher is never weaker than the given hasher.
+// Verify only requests a rehash of hashes with weaker parameters, so that the calibration
+// choosing a lower cost after a restart does not cause rehashes.
+func CalibratePasswordHasher(hasher PasswordHasher, target time.Duration) (PasswordHasher, error) {
+	switch base := hasher.(type) {
+	case *Argon2idHasher:
+		return calibrate(target, func(i int) (PasswordHasher, bool) {
+			candidate := *base
+			candidate.Time += uint32(i)
+			return &candidate, candidate.Time <= maxArgon2idTime
+		})
+	case *ScryptHasher:
+		return calibrate(target, func(i int) (PasswordHasher, bool) {
+			candidate := *base
+			candidate.LogN += uint8(i)
+			return &candidate, candidate.LogN <= maxScryptLogN
+		})
+	case *BcryptHasher:
+		return calibrate(target, func(i int) (PasswordHasher, bool) {
+			candidate := *base
+			candidate.Cost += i
+			return &candidate, candidate.Cost <= bcrypt.MaxCost
+		})
+	}
+	return nil, fmt.Errorf("unable to calibrate password hasher of type %T", hasher)
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMissingCertificate means the peer did not present a verified client certificate.
+// It wraps ErrMissingToken.
+var ErrMissingCertificate = fmt.Errorf("%w: no verified client certificate", ErrMissingToken)
+
+// CertificateRule maps a client certificate identity to a principal
+//
+// Exactly one of URI, DNSName or CommonName should be set.
+// The matched identity becomes the subject of the principal.
+type CertificateRule struct {
+	// URI matches a URI SAN, e.g. a SPIFFE ID. A trailing `*` matches any suffix,
+	// e.g. `spiffe://example.org/ns/prod/*`.
+	URI string
+	// DNSName matches a DNS SAN. A leading `*.` matches a single label,
+	// e.g. `*.billing.svc.cluster.local`.
+	DNSName string
+	// CommonName matches the common name of the certificate subject
+	CommonName string
+	// Scopes are granted to matching certificates
+	Scopes []string
+}
+
+// CertificateMapper maps verified client certificates to principals
+type CertificateMapper struct {
+	// Rules are checked in order, the first matching rule is used
+	Rules []CertificateRule
+}
+
+func matchURI(pattern, uri string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(uri, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == uri
+}
+
+func matchDNSName(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(strings.TrimSuffix(name, "."))
+	if strings.HasPrefix(pattern, "*.") {
+		parts := strings.SplitN(name, ".", 2)
+		return len(parts) == 2 && parts[0] != "" && "*."+parts[1] == pattern
+	}
+	return pattern == name
+}
+
+// match returns the identity of the certificate that matches the rule
+func (rule *CertificateRule) match(cert *x509.Certificate) (string, bool) {
+	switch {
+	case rule.URI != "":
+		for _, uri := range cert.URIs {
+			if matchURI(rule.URI, uri.String()) {
+				return uri.String(), true
+			}
+		}
+	case rule.DNSName != "":
+		for _, name := range cert.DNSNames {
+			if matchDNSName(rule.DNSName, name) {
+				return name, true
+			}
+		}
+	case rule.CommonName != "":
+		if cert.Subject.CommonName == rule.CommonName {
+			return cert.Subject.CommonName, true
+		}
+	}
+	return "", false
+}
+
+// Map returns the principal of a verified client certificate
+//
+// Certificates that match no rule are rejected with a *ValidationError with reason ErrCertificateNotAllowed.
+// The certificate must have been verified, e.g. by the TLS handshake.
+func (mapper *CertificateMapper) Map(cert *x509.Certificate) (*Principal, error) {
+	if cert == nil {
+		return nil, errors.New("missing certificate")
+	}
+	for i := range mapper.Rules {
+		if identity, ok := mapper.Rules[i].match(cert); ok {
+			return &Principal{
+				Subject: identity,
+				Scopes:  mapper.Rules[i].Scopes,
+				Method:  AuthMethodCertificate,
+			}, nil
+		}
+	}
+	return nil, &ValidationError{Reason: ErrCertificateNotAllowed}
+}
+
+// VerifiedPeerCertificate returns the leaf of the first verified certificate chain
+func VerifiedPeerCertificate(verifiedChains [][]*x509.Certificate) (*x509.Certificate, error) {
+	if len(verifiedChains) < 1 || len(verifiedChains[0]) < 1 {
+		return nil, ErrMissingCertificate
+	}
+	return verifiedChains[0][0], nil
+}
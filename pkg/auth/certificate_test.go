@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestCertificateMapper(t *testing.T) {
+	t.Parallel()
+	mapper := CertificateMapper{Rules: []CertificateRule{
+		{URI: "spiffe://example.org/ns/prod/*", Scopes: []string{"prod"}},
+		{DNSName: "*.billing.svc.cluster.local", Scopes: []string{"billing"}},
+		{CommonName: "legacy-client"},
+	}}
+	spiffeID, _ := url.Parse("spiffe://example.org/ns/prod/sa/api")
+	otherID, _ := url.Parse("spiffe://example.org/ns/dev/sa/api")
+
+	cases := []struct {
+		cert    *x509.Certificate
+		subject string
+		scopes  []string
+	}{
+		{&x509.Certificate{URIs: []*url.URL{otherID, spiffeID}}, spiffeID.String(), []string{"prod"}},
+		{&x509.Certificate{DNSNames: []string{"worker.billing.svc.cluster.local"}}, "worker.billing.svc.cluster.local", []string{"billing"}},
+		{&x509.Certificate{Subject: pkix.Name{CommonName: "legacy-client"}}, "legacy-client", nil},
+	}
+	for _, c := range cases {
+		principal, err := mapper.Map(c.cert)
+		if err != nil {
+			t.Fatalf("failed to map certificate: %v", err)
+		}
+		if principal.Subject != c.subject || principal.Method != AuthMethodCertificate || len(principal.Scopes) != len(c.scopes) {
+			t.Errorf("unexpected principal %+v, expected subject %q", principal, c.subject)
+		}
+	}
+
+	rejected := []*x509.Certificate{
+		{URIs: []*url.URL{otherID}},
+		// wildcards match a single label only
+		{DNSNames: []string{"a.worker.billing.svc.cluster.local", "billing.svc.cluster.local"}},
+		{Subject: pkix.Name{CommonName: "other-client"}},
+	}
+	for _, cert := range rejected {
+		if _, err := mapper.Map(cert); !errors.Is(err, ErrCertificateNotAllowed) {
+			t.Errorf("expected error %v but got %v", ErrCertificateNotAllowed, err)
+		}
+	}
+
+	if _, err := VerifiedPeerCertificate(nil); !errors.Is(err, ErrMissingToken) {
+		t.Errorf("expected error %v but got %v", ErrMissingToken, err)
+	}
+}
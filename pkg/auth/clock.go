@@ -0,0 +1,27 @@
+package auth
+
+import "time"
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is a function that implements Clock
+type ClockFunc func() time.Time
+
+// Now returns the current time
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock is the clock of the system
+var SystemClock Clock = ClockFunc(time.Now)
+
+// now returns the current time of the clock of the authenticator
+func (auth *Authenticator) now() time.Time {
+	if auth.Clock != nil {
+		return auth.Clock.Now()
+	}
+	return time.Now()
+}
@@ -1,10 +1,131 @@
 package auth
 
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
 // KeyConfig configures the keys that will be used for authentication
 type KeyConfig struct {
 	Jwks     string
 	JwksFile string
-	Key      string
-	KeyFile  string
+	// JwksURL is fetched and refreshed in the background to verify tokens signed by other services
+	JwksURL string
+	// Key and KeyFile are PEM encoded PKCS#1, SEC 1 or PKCS#8 private keys
+	Key     string
+	KeyFile string
+	// KeyPassphrase decrypts an encrypted PKCS#8 Key or KeyFile
+	KeyPassphrase string
+	// KeyPassphraseFile contains the passphrase of an encrypted PKCS#8 Key or KeyFile
+	KeyPassphraseFile string
+	// Certificate and CertificateFile are PEM encoded X.509 certificate chains starting with the leaf certificate.
+	// The public key of the leaf certificate is added to the JWK set including its `x5c` and `x5t` members.
+	Certificate     string
+	CertificateFile string
+	// Generate a signing key if none is configured.
+	// If KeyFile is set, the generated key and its JWK set are written to KeyFile and JwksFile
+	// with 0600 permissions and reused on later starts.
 	Generate bool
+	// Algorithm of generated signing keys (RS256, ES256, ES384, ES512 or EdDSA), defaults to RS256,
+	// or of the secret (HS256, HS384 or HS512), defaults to HS256
+	Algorithm string
+	// Secret and SecretFile are shared secrets to sign and verify tokens using HMAC instead of asymmetric keys.
+	// The secret must be at least 32, 48 or 64 bytes long for HS256, HS384 or HS512 respectively.
+	// The content of SecretFile is used as is, except for a trailing line ending.
+	Secret     string
+	SecretFile string
+	// EncryptionKey and EncryptionKeyFile are PEM encoded RSA or ECDSA private keys to encrypt and decrypt tokens.
+	// Like Key, they are decrypted using the key passphrase if encrypted.
+	EncryptionKey     string
+	EncryptionKeyFile string
+	// EncryptionPublicKey and EncryptionPublicKeyFile are PEM encoded public keys or certificates
+	// that tokens are encrypted for, e.g. by services that issue tokens they never validate.
+	// They take precedence over the public key of the EncryptionKey.
+	EncryptionPublicKey     string
+	EncryptionPublicKeyFile string
+	// EncryptionAlgorithm is RSA-OAEP or RSA-OAEP-256 (default) for RSA keys and ECDH-ES for ECDSA keys
+	EncryptionAlgorithm string
+	// RequireEncryption rejects tokens that are not encrypted
+	RequireEncryption bool
+}
+
+// passphrase returns the configured key passphrase
+func (config *KeyConfig) passphrase() ([]byte, error) {
+	if config.KeyPassphrase != "" && config.KeyPassphraseFile != "" {
+		return nil, errors.New("either a key passphrase or a key passphrase file can be configured, not both")
+	}
+	if config.KeyPassphraseFile != "" {
+		return LoadPassphraseFromFile(config.KeyPassphraseFile)
+	}
+	return []byte(config.KeyPassphrase), nil
+}
+
+func (config *KeyConfig) hasCertificate() bool {
+	return config.Certificate != "" || config.CertificateFile != ""
+}
+
+// certificates returns the configured certificate chain
+func (config *KeyConfig) certificates() ([]*x509.Certificate, error) {
+	if config.CertificateFile != "" {
+		return LoadCertificatesFromFile(config.CertificateFile)
+	}
+	return ParseCertificatesFromPEMData([]byte(config.Certificate))
+}
+
+// secret returns the configured shared secret
+func (config *KeyConfig) secret() (*SharedSecret, error) {
+	if config.Secret != "" && config.SecretFile != "" {
+		return nil, errors.New("either a secret or a secret file can be configured, not both")
+	}
+	if config.Key != "" || config.KeyFile != "" || config.Jwks != "" || config.JwksFile != "" ||
+		config.JwksURL != "" || config.hasCertificate() {
+		return nil, errors.New("a secret cannot be configured together with asymmetric keys")
+	}
+	key := []byte(config.Secret)
+	if config.SecretFile != "" {
+		var err error
+		if key, err = loadSecretFile(config.SecretFile); err != nil {
+			return nil, err
+		}
+	}
+	return NewSharedSecret(config.Algorithm, key)
+}
+
+func (config *KeyConfig) hasSecret() bool {
+	return config.Secret != "" || config.SecretFile != ""
+}
+
+// encryption returns the configured token encryption or nil if no encryption key is configured
+func (config *KeyConfig) encryption(passphrase []byte) (*TokenEncryption, error) {
+	encryption := &TokenEncryption{Algorithm: config.EncryptionAlgorithm, Required: config.RequireEncryption}
+	var err error
+	switch {
+	case config.EncryptionKeyFile != "":
+		encryption.PrivateKey, err = ParseEncryptedSigningKeyFromPEMFile(config.EncryptionKeyFile, passphrase)
+	case config.EncryptionKey != "":
+		encryption.PrivateKey, err = ParseEncryptedSigningKeyFromPEMData([]byte(config.EncryptionKey), passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key: %w", err)
+	}
+	switch {
+	case config.EncryptionPublicKeyFile != "":
+		encryption.PublicKey, err = ParsePublicKeyFromPEMFile(config.EncryptionPublicKeyFile)
+	case config.EncryptionPublicKey != "":
+		encryption.PublicKey, err = ParsePublicKeyFromPEMData([]byte(config.EncryptionPublicKey))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption public key: %v", err)
+	}
+	if encryption.PrivateKey == nil && encryption.PublicKey == nil {
+		if config.RequireEncryption {
+			return nil, errors.New("encryption is required but no encryption key is configured")
+		}
+		return nil, nil
+	}
+	if _, err := encryption.algorithm(); err != nil {
+		return nil, err
+	}
+	return encryption, nil
 }
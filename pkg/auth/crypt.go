@@ -1,13 +1,14 @@
 package auth
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
-	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
-	"math/big"
-	"time"
+	"fmt"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/lestrrat-go/jwx/jwk"
@@ -15,6 +16,9 @@ import (
 )
 
 // HashPassword creates a cryptograhic hash of a password
+//
+// The hash uses bcrypt, which ignores everything after the first 72 bytes of the password.
+// Use a PasswordHasher for other algorithms.
 func HashPassword(password string) (string, error) {
 	// bcrypt.MaxCost takes very very very long
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost+4)
@@ -37,42 +41,34 @@ func CheckPasswordHash(password, hash string) bool {
 }
 
 // JWK encodes a JSON web key
+//
+// Deprecated: JWKs are built using github.com/lestrrat-go/jwx/jwk, see PublicKeyJwk.
 type JWK struct {
 	KID       string `json:"kid"`
 	Algorithm string `json:"alg"`
-	E         string `json:"e"`
+	Crv       string `json:"crv,omitempty"`
+	E         string `json:"e,omitempty"`
 	KTY       string `json:"kty"`
-	N         string `json:"n"`
+	N         string `json:"n,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
 }
 
-// ToJwks converts a RSA public key to a JWK set
-func ToJwks(pub *rsa.PublicKey) (jwk.Set, error) {
-	jwkJSON, err := ToJwksJSON(pub)
-	if err != nil {
-		return nil, err
-	}
-	jwkSet, err := jwk.Parse(jwkJSON)
+// ToJwks converts a RSA, ECDSA or Ed25519 public key to a JWK set
+//
+// The key id is the RFC 7638 thumbprint of the public key.
+// Use JwkSetFromPublicKeys for multiple keys.
+func ToJwks(pub crypto.PublicKey) (jwk.Set, error) {
+	return JwkSetFromPublicKeys(pub)
+}
+
+// ToJwksJSON converts a RSA, ECDSA or Ed25519 public key to a JSON encoded JWK set
+func ToJwksJSON(pub crypto.PublicKey) ([]byte, error) {
+	jwkSet, err := ToJwks(pub)
 	if err != nil {
 		return nil, err
 	}
-	return jwkSet, nil
-}
-
-// ToJwksJSON converts a RSA public key to a JSON encoded JWK set
-func ToJwksJSON(pub *rsa.PublicKey) ([]byte, error) {
-	// See https://github.com/golang/crypto/blob/master/acme/jws.go#L90
-	// https://tools.ietf.org/html/rfc7518#section-6.3.1
-	n := pub.N
-	e := big.NewInt(int64(pub.E))
-	// Field order is important.
-	// See https://tools.ietf.org/html/rfc7638#section-3.3 for details.
-	return json.Marshal(JWK{
-		KID:       "0",
-		Algorithm: "RS256",
-		E:         base64.RawURLEncoding.EncodeToString(e.Bytes()),
-		KTY:       "RSA",
-		N:         base64.RawURLEncoding.EncodeToString(n.Bytes()),
-	})
+	return json.Marshal(jwkSet)
 }
 
 // ToPEM converts a RSA private key into PEM format
@@ -85,23 +81,90 @@ func ToPEM(key *rsa.PrivateKey) []byte {
 	)
 }
 
-// SignJwtClaims signs JWT claims using RS256 and returns the token string
+// PrivateKeyToPEM converts a RSA, ECDSA or Ed25519 private key into PEM format
+//
+// RSA keys are encoded as PKCS#1, ECDSA keys as SEC 1 and Ed25519 keys as PKCS#8
+func PrivateKeyToPEM(key crypto.Signer) ([]byte, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return ToPEM(key), nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	case ed25519.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", key)
+}
+
+// SignJwtClaims signs JWT claims using the algorithm of the signing key and returns the token string
+//
+// A unique `jti` is assigned unless the claims already have an id.
+// If the claims implement AuthMethodsClaims and the subject authenticated using more than one method,
+// e.g. a password and a one-time password, AMRMultiFactor is added to the `amr` claim.
 func (auth *Authenticator) SignJwtClaims(claims Claims) (string, error) {
-	expirationTime := time.Now().Add(auth.ExpiresAfter)
+	now := auth.now()
+	expirationTime := now.Add(auth.ExpiresAfter)
 
 	// set structured JWT claims set
 	// https://pkg.go.dev/github.com/golang-jwt/jwt/v4#RegisteredClaims
 	// https://datatracker.ietf.org/doc/html/rfc7519#section-4.1
 	reg := claims.GetRegisteredClaims()
+	reg.IssuedAt = jwt.NewNumericDate(now)
 	reg.ExpiresAt = jwt.NewNumericDate(expirationTime)
 	reg.Issuer = auth.Issuer
 	reg.Audience = jwt.ClaimStrings([]string{auth.Audience})
+	return auth.sign(claims)
+}
+
+// sign signs claims using the shared secret or the current signing key and assigns a unique `jti` if not set
+//
+// If token encryption is configured, the signed token is encrypted.
+func (auth *Authenticator) sign(claims Claims) (string, error) {
+	if reg := claims.GetRegisteredClaims(); reg.ID == "" {
+		id, err := randomToken(16)
+		if err != nil {
+			return "", err
+		}
+		reg.ID = id
+	}
+	setAuthMethods(claims)
+	signed, err := auth.signJws(claims)
+	if err != nil {
+		return "", err
+	}
+	auth.signed(claims.GetRegisteredClaims().ExpiresAt)
+	if auth.Encryption != nil {
+		return auth.Encryption.encrypt(signed)
+	}
+	return signed, nil
+}
+
+func (auth *Authenticator) signJws(claims Claims) (string, error) {
+	if auth.Secret != nil {
+		return auth.Secret.sign(claims)
+	}
+
+	signingKey, err := auth.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	method, err := SigningMethodForKey(signingKey.Key.Public())
+	if err != nil {
+		return "", err
+	}
 
 	// create the token
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	token.Header["kid"] = "0"
-	token.Header["alg"] = "RS256"
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = signingKey.ID
 
 	// sign the token
-	return token.SignedString(auth.SignKey)
+	return token.SignedString(signingKey.Key)
 }
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// ContentEncryptionAlgorithm is the algorithm used to encrypt the signed token inside a JWE
+const ContentEncryptionAlgorithm = jwa.A256GCM
+
+// TokenEncryption encrypts signed tokens into nested JWS-in-JWE tokens (RFC 7519, section 5.2),
+// so that clients cannot read the claims of their tokens.
+//
+// Validate decrypts tokens before verifying the signature of the nested token.
+type TokenEncryption struct {
+	// Algorithm is the key management algorithm, RSA-OAEP or RSA-OAEP-256 for RSA keys and ECDH-ES for ECDSA keys.
+	// Defaults to RSA-OAEP-256 or ECDH-ES depending on the type of key.
+	Algorithm string
+	// PublicKey of the recipient that tokens are encrypted for, defaults to the public key of PrivateKey
+	PublicKey crypto.PublicKey
+	// PrivateKey decrypts tokens, services that only issue encrypted tokens may omit it
+	PrivateKey crypto.Signer
+	// Required rejects tokens that are not encrypted, otherwise unencrypted tokens are accepted as well
+	Required bool
+}
+
+// NewTokenEncryption creates a token encryption for a RSA or ECDSA private key
+//
+// See TokenEncryption for the supported algorithms, an empty algorithm selects the default for the key.
+func NewTokenEncryption(alg string, key crypto.Signer) (*TokenEncryption, error) {
+	encryption := &TokenEncryption{Algorithm: alg, PrivateKey: key}
+	if _, err := encryption.algorithm(); err != nil {
+		return nil, err
+	}
+	return encryption, nil
+}
+
+// publicKey returns the public key that tokens are encrypted for
+func (encryption *TokenEncryption) publicKey() crypto.PublicKey {
+	if encryption.PublicKey == nil && encryption.PrivateKey != nil {
+		return encryption.PrivateKey.Public()
+	}
+	return encryption.PublicKey
+}
+
+// algorithm returns the key management algorithm after checking that it matches the type of key
+func (encryption *TokenEncryption) algorithm() (jwa.KeyEncryptionAlgorithm, error) {
+	alg := jwa.KeyEncryptionAlgorithm(encryption.Algorithm)
+	switch encryption.publicKey().(type) {
+	case *rsa.PublicKey:
+		switch alg {
+		case "":
+			return jwa.RSA_OAEP_256, nil
+		case jwa.RSA_OAEP, jwa.RSA_OAEP_256:
+			return alg, nil
+		}
+	case *ecdsa.PublicKey:
+		switch alg {
+		case "", jwa.ECDH_ES:
+			return jwa.ECDH_ES, nil
+		}
+	case nil:
+		return "", errors.New("missing token encryption key")
+	default:
+		return "", fmt.Errorf("unsupported token encryption key type %T", encryption.publicKey())
+	}
+	return "", fmt.Errorf("unsupported token encryption algorithm %q for key type %T", alg, encryption.publicKey())
+}
+
+// encrypt encrypts a signed token for the public key
+func (encryption *TokenEncryption) encrypt(token string) (string, error) {
+	alg, err := encryption.algorithm()
+	if err != nil {
+		return "", err
+	}
+	pub := encryption.publicKey()
+	kid, err := Thumbprint(pub)
+	if err != nil {
+		return "", err
+	}
+	key, err := jwk.New(pub)
+	if err != nil {
+		return "", err
+	}
+	if err := key.Set(jwk.KeyIDKey, kid); err != nil {
+		return "", err
+	}
+	headers := jwe.NewHeaders()
+	if err := headers.Set(jwe.ContentTypeKey, "JWT"); err != nil {
+		return "", err
+	}
+	encrypted, err := jwe.Encrypt([]byte(token), alg, key, ContentEncryptionAlgorithm, jwa.NoCompress, jwe.WithProtectedHeaders(headers))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt token: %v", err)
+	}
+	return string(encrypted), nil
+}
+
+// decrypt decrypts a nested token after checking that the algorithms match the private key
+func (encryption *TokenEncryption) decrypt(token string) (string, error) {
+	if encryption.PrivateKey == nil {
+		return "", fmt.Errorf("%w: missing decryption key", ErrDecryptionFailed)
+	}
+	alg, err := encryption.algorithm()
+	if err != nil {
+		return "", err
+	}
+	message, err := jwe.ParseString(token)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	}
+	headers := message.ProtectedHeaders()
+	if headers.Algorithm() != alg || headers.ContentEncryption() != ContentEncryptionAlgorithm {
+		return "", fmt.Errorf("%w: expected %v with %v, but got %v with %v",
+			ErrDecryptionFailed, alg, ContentEncryptionAlgorithm, headers.Algorithm(), headers.ContentEncryption())
+	}
+	if !strings.EqualFold(headers.ContentType(), "JWT") {
+		return "", fmt.Errorf("%w: expected nested JWT, but got content type %q", ErrDecryptionFailed, headers.ContentType())
+	}
+	payload, err := jwe.Decrypt([]byte(token), alg, encryption.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+	}
+	return string(payload), nil
+}
+
+// isEncrypted reports whether a token is in JWE compact serialization, which has five parts
+func isEncrypted(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+// decrypt returns the nested token of an encrypted token
+//
+// Unencrypted tokens are returned as is unless encryption is required.
+func (auth *Authenticator) decrypt(token string) (string, error) {
+	switch encrypted := isEncrypted(token); {
+	case encrypted && auth.Encryption == nil:
+		return "", fmt.Errorf("%w: token encryption is not configured", ErrDecryptionFailed)
+	case encrypted:
+		return auth.Encryption.decrypt(token)
+	case auth.Encryption != nil && auth.Encryption.Required:
+		return "", ErrEncryptionRequired
+	}
+	return token, nil
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/lestrrat-go/jwx/jwe"
+)
+
+// encryptionKeyPEM generates a private key for token encryption and encodes it as PEM
+func encryptionKeyPEM(t *testing.T, alg string) []byte {
+	t.Helper()
+	key, err := GenerateSigningKey(alg)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM, err := PrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	return keyPEM
+}
+
+func TestEncryptedTokenRoundTrip(t *testing.T) {
+	test := new(test).setup(t)
+	cases := []struct {
+		keyAlg string
+		alg    string
+	}{
+		{"RS256", ""},
+		{"RS256", "RSA-OAEP"},
+		{"ES256", ""},
+		{"ES384", "ECDH-ES"},
+		{"ES512", "ECDH-ES"},
+	}
+	for _, c := range cases {
+		key, err := GenerateSigningKey(c.keyAlg)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		encryption, err := NewTokenEncryption(c.alg, key)
+		if err != nil {
+			t.Fatalf("failed to setup %s encryption with %s key: %v", c.alg, c.keyAlg, err)
+		}
+		authenticator := &Authenticator{
+			ExpiresAfter: time.Minute,
+			SignKey:      test.authenticator.SignKey,
+			JwkSet:       test.authenticator.JwkSet,
+			Encryption:   encryption,
+		}
+		token, err := authenticator.SignJwtClaims(&testClaims{UserID: "tenant-123"})
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		message, err := jwe.ParseString(token)
+		if err != nil {
+			t.Fatalf("expected JWE compact serialization but got %q: %v", token, err)
+		}
+		if headers := message.ProtectedHeaders(); headers.ContentType() != "JWT" || headers.ContentEncryption() != ContentEncryptionAlgorithm {
+			t.Errorf("expected nested JWT encrypted with %v but got %v with %v", ContentEncryptionAlgorithm, headers.ContentType(), headers.ContentEncryption())
+		}
+		for _, part := range strings.Split(token, ".") {
+			decoded, _ := base64.RawURLEncoding.DecodeString(part)
+			if strings.Contains(string(decoded), "tenant-123") || strings.Contains(part, "tenant-123") {
+				t.Errorf("expected claims to be confidential but found them in %q", decoded)
+			}
+		}
+
+		claims := &testClaims{}
+		if valid, _, err := authenticator.Validate(token, claims); err != nil || !valid {
+			t.Fatalf("expected encrypted token to be valid: %v", err)
+		}
+		if claims.UserID != "tenant-123" {
+			t.Errorf("expected user id tenant-123 but got %q", claims.UserID)
+		}
+
+		// services without the decryption key cannot validate the token
+		if _, _, err := test.authenticator.Validate(token, &testClaims{}); !errors.Is(err, ErrDecryptionFailed) {
+			t.Errorf("expected error %v but got %v", ErrDecryptionFailed, err)
+		}
+	}
+}
+
+func TestTokenEncryptionFromKeyConfig(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	key, err := GenerateSigningKey("RS256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM, err := PrivateKeyToPKCS8PEM(key, []byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	pubPEM, err := PublicKeyToPEM(key.Public())
+	if err != nil {
+		t.Fatalf("failed to encode public key: %v", err)
+	}
+	keyFile := filepath.Join(dir, "encryption.pem")
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	// the issuer only knows the public encryption key
+	issuer := &Authenticator{ExpiresAfter: time.Minute}
+	if err := issuer.SetupKeys(&KeyConfig{Generate: true, Algorithm: "ES256", EncryptionPublicKey: string(pubPEM)}); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	signKeyPEM, err := PrivateKeyToPEM(issuer.SignKey)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	validator := &Authenticator{ExpiresAfter: time.Minute}
+	if err := validator.SetupKeys(&KeyConfig{
+		Key:               string(signKeyPEM),
+		EncryptionKeyFile: keyFile,
+		KeyPassphrase:     "secret",
+		RequireEncryption: true,
+	}); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+
+	token, err := issuer.SignJwtClaims(&testClaims{UserID: "123"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if valid, _, err := validator.Validate(token, &testClaims{}); err != nil || !valid {
+		t.Errorf("expected encrypted token to be valid: %v", err)
+	}
+	if _, _, err := issuer.Validate(token, &testClaims{}); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("expected error %v without decryption key but got %v", ErrDecryptionFailed, err)
+	}
+
+	// the nested token is accepted only if it is encrypted
+	issuer.Encryption = nil
+	plainToken, err := issuer.SignJwtClaims(&testClaims{UserID: "123"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, _, err := validator.Validate(plainToken, &testClaims{}); !errors.Is(err, ErrEncryptionRequired) {
+		t.Errorf("expected error %v but got %v", ErrEncryptionRequired, err)
+	}
+	validator.Encryption.Required = false
+	if valid, _, err := validator.Validate(plainToken, &testClaims{}); err != nil || !valid {
+		t.Errorf("expected unencrypted token to be valid if encryption is optional: %v", err)
+	}
+}
+
+func TestTokenEncryptionRejectsMismatchingAlgorithms(t *testing.T) {
+	test := new(test).setup(t)
+	key, err := GenerateSigningKey("RS256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signed, err := test.authenticator.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	oaep := &TokenEncryption{Algorithm: "RSA-OAEP", PrivateKey: key}
+	token, err := oaep.encrypt(signed)
+	if err != nil {
+		t.Fatalf("failed to encrypt token: %v", err)
+	}
+	validator := &Authenticator{JwkSet: test.authenticator.JwkSet, Encryption: &TokenEncryption{Algorithm: "RSA-OAEP-256", PrivateKey: key}}
+	if _, _, err := validator.Validate(token, &testClaims{}); !errors.Is(err, ErrDecryptionFailed) {
+		t.Errorf("expected error %v but got %v", ErrDecryptionFailed, err)
+	}
+
+	// the nested token must be signed
+	nested, err := jwt.NewWithClaims(jwt.SigningMethodNone, &testClaims{}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("failed to create unsigned token: %v", err)
+	}
+	if token, err = oaep.encrypt(nested); err != nil {
+		t.Fatalf("failed to encrypt token: %v", err)
+	}
+	validator.Encryption.Algorithm = "RSA-OAEP"
+	if valid, _, err := validator.Validate(token, &testClaims{}); valid || err == nil {
+		t.Errorf("expected unsigned nested token to be rejected")
+	}
+
+	invalid := []struct {
+		alg     string
+		keyPEM  []byte
+		message string
+	}{
+		{"ECDH-ES", encryptionKeyPEM(t, "RS256"), "ECDH-ES with RSA key"},
+		{"RSA-OAEP", encryptionKeyPEM(t, "ES256"), "RSA-OAEP with ECDSA key"},
+		{"", encryptionKeyPEM(t, "EdDSA"), "Ed25519 key"},
+	}
+	for _, c := range invalid {
+		config := &KeyConfig{Generate: true, EncryptionKey: string(c.keyPEM), EncryptionAlgorithm: c.alg}
+		if err := (&Authenticator{}).SetupKeys(config); err == nil {
+			t.Errorf("expected token encryption using %s to be rejected", c.message)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Errors returned when validating a token
+//
+// Validate wraps them in a *ValidationError, use errors.Is to check for a specific reason.
+var (
+	ErrTokenInvalid          = errors.New("token is invalid")
+	ErrTokenMalformed        = jwt.ErrTokenMalformed
+	ErrDecryptionFailed      = errors.New("token could not be decrypted")
+	ErrEncryptionRequired    = errors.New("token must be encrypted")
+	ErrInvalidSignature      = jwt.ErrTokenSignatureInvalid
+	ErrInvalidSigningMethod  = errors.New("token signing method does not match key")
+	ErrUnknownKeyID          = errors.New("token is signed by an unknown key")
+	ErrTokenExpired          = jwt.ErrTokenExpired
+	ErrTokenNotValidYet      = jwt.ErrTokenNotValidYet
+	ErrTokenUsedBeforeIssued = jwt.ErrTokenUsedBeforeIssued
+	ErrInvalidIssuer         = jwt.ErrTokenInvalidIssuer
+	ErrInvalidAudience       = jwt.ErrTokenInvalidAudience
+	ErrTokenTooOld           = errors.New("token exceeds maximum age")
+	ErrMissingClaim          = errors.New("token is missing a required claim")
+	ErrTokenRevoked          = errors.New("token has been revoked")
+	ErrInvalidAPIKey         = errors.New("api key is invalid")
+	ErrCertificateNotAllowed = errors.New("client certificate is not allowed")
+)
+
+// Errors returned when exchanging a refresh token
+var (
+	// ErrRefreshTokenInvalid means the refresh token is unknown, expired or revoked
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+	// ErrRefreshTokenReused means an already exchanged refresh token was used again
+	ErrRefreshTokenReused = errors.New("refresh token has already been used")
+)
+
+// Errors returned by authentication middleware
+var (
+	// ErrMissingToken means the request did not carry any credentials
+	ErrMissingToken = errors.New("missing token")
+	// ErrInsufficientScope means the caller is authenticated but lacks a required role or scope
+	ErrInsufficientScope = errors.New("insufficient scope")
+)
+
+// validationReasons are checked in order to find the reason of an error
+var validationReasons = []error{
+	ErrTokenMalformed,
+	ErrDecryptionFailed,
+	ErrEncryptionRequired,
+	ErrInvalidSigningMethod,
+	ErrUnknownKeyID,
+	ErrInvalidSignature,
+	ErrTokenExpired,
+	ErrTokenNotValidYet,
+	ErrTokenUsedBeforeIssued,
+	ErrInvalidIssuer,
+	ErrInvalidAudience,
+	ErrTokenTooOld,
+	ErrMissingClaim,
+}
+
+// ValidationError describes why a token is invalid
+type ValidationError struct {
+	// Reason is one of the validation errors, e.g. ErrTokenExpired
+	Reason error
+	// Err is the underlying error, if any
+	Err error
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	if e.Err == nil {
+		return e.Reason.Error()
+	}
+	if errors.Is(e.Err, e.Reason) {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v: %v", e.Reason, e.Err)
+}
+
+// Is reports whether the reason of the error matches target.
+// All validation errors match ErrTokenInvalid.
+func (e *ValidationError) Is(target error) bool {
+	return e.Reason == target || target == ErrTokenInvalid
+}
+
+// Unwrap returns the underlying error
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// newValidationError wraps an error into a *ValidationError with a matching reason
+func newValidationError(err error) *ValidationError {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr
+	}
+	for _, reason := range validationReasons {
+		if errors.Is(err, reason) {
+			return &ValidationError{Reason: reason, Err: err}
+		}
+	}
+	return &ValidationError{Reason: ErrTokenInvalid, Err: err}
+}
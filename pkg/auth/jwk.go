@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// Thumbprint returns the base64url encoded RFC 7638 SHA-256 thumbprint of a RSA, ECDSA or Ed25519 public key
+//
+// The thumbprint only depends on the key material and is used as the key id of signing keys,
+// so that independently generated keys never share a key id.
+func Thumbprint(pub crypto.PublicKey) (string, error) {
+	if _, err := SigningMethodForKey(pub); err != nil {
+		return "", err
+	}
+	key, err := jwk.New(pub)
+	if err != nil {
+		return "", err
+	}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// PublicJwk converts the public part of a signing key to a JWK with the given key id
+func PublicJwk(id string, key crypto.Signer) (jwk.Key, error) {
+	return PublicKeyJwk(id, key.Public())
+}
+
+// PublicKeyJwk converts a RSA, ECDSA or Ed25519 public key to a JWK with the given key id
+//
+// Besides the key id (`kid`), the JWK includes the signing algorithm (`alg`),
+// and marks the key for signature verification only (`use`, `key_ops`).
+func PublicKeyJwk(id string, pub crypto.PublicKey) (jwk.Key, error) {
+	method, err := SigningMethodForKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	key, err := jwk.New(pub)
+	if err != nil {
+		return nil, err
+	}
+	fields := []jwkField{
+		{jwk.KeyIDKey, id},
+		{jwk.AlgorithmKey, method.Alg()},
+		{jwk.KeyUsageKey, jwk.ForSignature},
+		{jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpVerify}},
+	}
+	if err := setJwkFields(key, fields); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+type jwkField struct {
+	name  string
+	value interface{}
+}
+
+func setJwkFields(key jwk.Key, fields []jwkField) error {
+	for _, field := range fields {
+		if err := key.Set(field.name, field.value); err != nil {
+			return fmt.Errorf("failed to set JWK %s: %v", field.name, err)
+		}
+	}
+	return nil
+}
+
+// JwkSetFromPublicKeys converts RSA, ECDSA or Ed25519 public keys to a JWK set
+//
+// The key id of each JWK is the RFC 7638 thumbprint of the public key.
+func JwkSetFromPublicKeys(pubs ...crypto.PublicKey) (jwk.Set, error) {
+	set := jwk.NewSet()
+	for _, pub := range pubs {
+		id, err := Thumbprint(pub)
+		if err != nil {
+			return nil, err
+		}
+		key, err := PublicKeyJwk(id, pub)
+		if err != nil {
+			return nil, err
+		}
+		if err := addJwk(set, key); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// addJwk adds key to set unless the set already contains a key with the same key id
+func addJwk(set jwk.Set, key jwk.Key) error {
+	if _, exists := set.LookupKeyID(key.KeyID()); exists {
+		return fmt.Errorf("duplicate key id %q", key.KeyID())
+	}
+	set.Add(key)
+	return nil
+}
+
+// CertificateChainJwk converts the public key of the leaf certificate of chain to a JWK with the given key id
+//
+// The chain starts with the leaf certificate and each certificate must be signed by the next one.
+// The JWK includes the chain (`x5c`) and the SHA-1 and SHA-256 thumbprints of the leaf certificate (`x5t`, `x5t#S256`).
+func CertificateChainJwk(id string, chain []*x509.Certificate) (jwk.Key, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("empty certificate chain")
+	}
+	for i := 0; i+1 < len(chain); i++ {
+		if err := chain[i].CheckSignatureFrom(chain[i+1]); err != nil {
+			return nil, fmt.Errorf("certificate %d of chain is not signed by its successor: %v", i, err)
+		}
+	}
+	leaf := chain[0]
+	key, err := PublicKeyJwk(id, leaf.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	encoded := make([]string, len(chain))
+	for i, cert := range chain {
+		encoded[i] = base64.StdEncoding.EncodeToString(cert.Raw)
+	}
+	sha1Thumbprint := sha1.Sum(leaf.Raw)
+	sha256Thumbprint := sha256.Sum256(leaf.Raw)
+	fields := []jwkField{
+		{jwk.X509CertChainKey, encoded},
+		{jwk.X509CertThumbprintKey, base64.RawURLEncoding.EncodeToString(sha1Thumbprint[:])},
+		{jwk.X509CertThumbprintS256Key, base64.RawURLEncoding.EncodeToString(sha256Thumbprint[:])},
+	}
+	if err := setJwkFields(key, fields); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// JwkSetFromCertificateChains converts the leaf certificates of chains to a JWK set
+//
+// The key id of each JWK is the RFC 7638 thumbprint of the public key of its leaf certificate.
+func JwkSetFromCertificateChains(chains ...[]*x509.Certificate) (jwk.Set, error) {
+	set := jwk.NewSet()
+	for _, chain := range chains {
+		if len(chain) == 0 {
+			return nil, errors.New("empty certificate chain")
+		}
+		id, err := Thumbprint(chain[0].PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		key, err := CertificateChainJwk(id, chain)
+		if err != nil {
+			return nil, err
+		}
+		if err := addJwk(set, key); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+func TestThumbprint(t *testing.T) {
+	t.Parallel()
+	// example key of https://datatracker.ietf.org/doc/html/rfc7638#section-3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatalf("failed to decode modulus: %v", err)
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+	thumbprint, err := Thumbprint(pub)
+	if err != nil {
+		t.Fatalf("failed to compute thumbprint: %v", err)
+	}
+	if expected := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; thumbprint != expected {
+		t.Errorf("expected thumbprint %q but got %q", expected, thumbprint)
+	}
+}
+
+func TestJwkSetFromPublicKeys(t *testing.T) {
+	t.Parallel()
+	var pubs []crypto.PublicKey
+	for _, alg := range []string{"ES256", "ES384", "ES512", "EdDSA", "ES256"} {
+		key, err := GenerateSigningKey(alg)
+		if err != nil {
+			t.Fatalf("failed to generate %s key: %v", alg, err)
+		}
+		pubs = append(pubs, key.Public())
+	}
+	set, err := JwkSetFromPublicKeys(pubs...)
+	if err != nil {
+		t.Fatalf("failed to build JWK set: %v", err)
+	}
+	if set.Len() != len(pubs) {
+		t.Fatalf("expected %d keys but got %d", len(pubs), set.Len())
+	}
+
+	encoded, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to encode JWK set: %v", err)
+	}
+	var decoded struct {
+		Keys []struct {
+			KID    string   `json:"kid"`
+			Alg    string   `json:"alg"`
+			Use    string   `json:"use"`
+			KeyOps []string `json:"key_ops"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("failed to decode JWK set: %v", err)
+	}
+	for i, key := range decoded.Keys {
+		kid, _ := Thumbprint(pubs[i])
+		if key.KID != kid || key.Alg == "" || key.Use != "sig" || len(key.KeyOps) != 1 || key.KeyOps[0] != "verify" {
+			t.Errorf("unexpected JWK %+v, expected key id %q", key, kid)
+		}
+	}
+
+	if _, err := JwkSetFromPublicKeys(pubs[0], pubs[0]); err == nil {
+		t.Errorf("expected duplicate keys to be rejected")
+	}
+}
+
+func TestSigningKeyIDDefaultsToThumbprint(t *testing.T) {
+	test := new(test).setup(t)
+	signingKey, err := test.authenticator.SigningKey()
+	if err != nil {
+		t.Fatalf("failed to get signing key: %v", err)
+	}
+	if kid, _ := Thumbprint(signingKey.Key.Public()); signingKey.ID != kid {
+		t.Errorf("expected key id %q but got %q", kid, signingKey.ID)
+	}
+}
+
+func TestSigningKeyIDFromPersistedJwkSet(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	key, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM, err := PrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	// JWK sets written by earlier versions use a fixed key id
+	legacy, err := PublicKeyJwk(DefaultKeyID, key.Public())
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	set := jwk.NewSet()
+	set.Add(legacy)
+	jwksJSON, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to encode JWK set: %v", err)
+	}
+	config := KeyConfig{
+		KeyFile:  filepath.Join(dir, "key.pem"),
+		JwksFile: filepath.Join(dir, "jwks.json"),
+	}
+	if err := os.WriteFile(config.KeyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	if err := os.WriteFile(config.JwksFile, jwksJSON, 0o600); err != nil {
+		t.Fatalf("failed to write JWK set: %v", err)
+	}
+
+	authenticator := &Authenticator{ExpiresAfter: time.Minute}
+	if err := authenticator.SetupKeys(&config); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	token, err := authenticator.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	valid, parsed, err := authenticator.Validate(token, &testClaims{})
+	if err != nil || !valid {
+		t.Fatalf("expected token to be valid: %v", err)
+	}
+	if kid := parsed.Header["kid"]; kid != DefaultKeyID {
+		t.Errorf("expected key id %q of the JWK set but got %q", DefaultKeyID, kid)
+	}
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// DefaultKeyID was the key id of signing keys that are configured directly
+//
+// Deprecated: key ids are derived from the RFC 7638 thumbprint of the public key, see Thumbprint.
+// Tokens signed with this key id remain valid as long as the JWK set contains it.
+const DefaultKeyID = "0"
+
+// SigningKey is a private signing key with its key id
+type SigningKey struct {
+	ID  string
+	Key crypto.Signer
+}
+
+type keyringEntry struct {
+	key jwk.Key
+	// retired keys expire once all tokens they signed have expired
+	expiresAt time.Time
+}
+
+// Keyring holds the current signing key and retired keys that remain valid for verification
+//
+// Its methods expire retired keys using the system clock,
+// an Authenticator uses its own Clock instead.
+type Keyring struct {
+	mu      sync.RWMutex
+	current *SigningKey
+	keys    map[string]*keyringEntry
+}
+
+// NewKeyring creates a new keyring with an initial signing key
+func NewKeyring(id string, key crypto.Signer) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string]*keyringEntry)}
+	// there is no previous signing key to retire
+	if err := keyring.rotate(id, key, time.Time{}, time.Now()); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// NewKeyID generates a new random key id
+func NewKeyID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Current returns the current signing key
+func (keyring *Keyring) Current() *SigningKey {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	return keyring.current
+}
+
+// Rotate makes key the current signing key and retires the previous signing key.
+//
+// The retired key remains valid for verification until retireAt,
+// which must cover the expiry of the tokens it signed plus any leeway.
+// A zero retireAt is rejected, since the retired key would remain valid forever.
+func (keyring *Keyring) Rotate(id string, key crypto.Signer, retireAt time.Time) error {
+	if retireAt.IsZero() {
+		return errors.New("missing retire time of the previous signing key")
+	}
+	return keyring.rotate(id, key, retireAt, time.Now())
+}
+
+func (keyring *Keyring) rotate(id string, key crypto.Signer, retireAt, now time.Time) error {
+	if key == nil {
+		return errors.New("missing signing key")
+	}
+	publicKey, err := PublicJwk(id, key)
+	if err != nil {
+		return err
+	}
+
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	if _, exists := keyring.keys[id]; exists {
+		return fmt.Errorf("key with id %q already exists", id)
+	}
+	if keyring.current != nil {
+		keyring.keys[keyring.current.ID].expiresAt = retireAt
+	}
+	keyring.current = &SigningKey{ID: id, Key: key}
+	keyring.keys[id] = &keyringEntry{key: publicKey}
+	keyring.prune(now)
+	return nil
+}
+
+// Prune removes retired keys that have expired
+func (keyring *Keyring) Prune() {
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+	keyring.prune(time.Now())
+}
+
+func (keyring *Keyring) prune(now time.Time) {
+	for id, entry := range keyring.keys {
+		if entry.expired(now) {
+			delete(keyring.keys, id)
+		}
+	}
+}
+
+func (entry *keyringEntry) expired(now time.Time) bool {
+	return !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt)
+}
+
+// LookupKeyID finds the public key with the given id if it has not expired
+func (keyring *Keyring) LookupKeyID(id string) (jwk.Key, bool) {
+	return keyring.lookupKeyID(id, time.Now())
+}
+
+func (keyring *Keyring) lookupKeyID(id string, now time.Time) (jwk.Key, bool) {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	entry, ok := keyring.keys[id]
+	if !ok || entry.expired(now) {
+		return nil, false
+	}
+	return entry.key, true
+}
+
+// JwkSet returns the public keys of the current and all retired keys that have not expired
+func (keyring *Keyring) JwkSet() jwk.Set {
+	return keyring.jwkSet(time.Now())
+}
+
+func (keyring *Keyring) jwkSet(now time.Time) jwk.Set {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	ids := make([]string, 0, len(keyring.keys))
+	for id := range keyring.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	set := jwk.NewSet()
+	for _, id := range ids {
+		if entry := keyring.keys[id]; !entry.expired(now) {
+			set.Add(entry.key)
+		}
+	}
+	return set
+}
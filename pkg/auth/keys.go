@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
@@ -16,6 +20,18 @@ type RSAKeyPair struct {
 	PublicKey  *rsa.PublicKey
 }
 
+// ECDSAKeyPair is an ECDSA key pair
+type ECDSAKeyPair struct {
+	PrivateKey *ecdsa.PrivateKey
+	PublicKey  *ecdsa.PublicKey
+}
+
+// Ed25519KeyPair is an Ed25519 key pair
+type Ed25519KeyPair struct {
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
 // GenerateRSAKeyPair generates an RSA key pair
 func GenerateRSAKeyPair() (*RSAKeyPair, error) {
 	privateKey, err := rsa.GenerateKey(rand.Reader, 4096)
@@ -33,22 +49,84 @@ func GenerateRSAKeyPair() (*RSAKeyPair, error) {
 	}, nil
 }
 
-// ParseSigningKeyFromPEMData parses a private RSA signing key from PEM data
-func ParseSigningKeyFromPEMData(keyData []byte) (*rsa.PrivateKey, error) {
-	return jwt.ParseRSAPrivateKeyFromPEM(keyData)
+// GenerateECDSAKeyPair generates an ECDSA key pair on the given curve
+func GenerateECDSAKeyPair(curve elliptic.Curve) (*ECDSAKeyPair, error) {
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ECDSAKeyPair{
+		PrivateKey: privateKey,
+		PublicKey:  &privateKey.PublicKey,
+	}, nil
 }
 
-// ParseSigningKeyFromPEMFile parses a private RSA signing key from a PEM file
-func ParseSigningKeyFromPEMFile(path string) (*rsa.PrivateKey, error) {
-	keyData, err := ioutil.ReadFile(path)
+// GenerateEd25519KeyPair generates an Ed25519 key pair
+func GenerateEd25519KeyPair() (*Ed25519KeyPair, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		return nil, err
 	}
-	key, err := ParseSigningKeyFromPEMData(keyData)
+	return &Ed25519KeyPair{
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	}, nil
+}
+
+// GenerateSigningKey generates a private signing key for the given algorithm (RS256, ES256, ES384, ES512 or EdDSA)
+func GenerateSigningKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case "", jwt.SigningMethodRS256.Alg():
+		keyPair, err := GenerateRSAKeyPair()
+		if err != nil {
+			return nil, err
+		}
+		return keyPair.PrivateKey, nil
+	case jwt.SigningMethodES256.Alg():
+		return generateECDSASigningKey(elliptic.P256())
+	case jwt.SigningMethodES384.Alg():
+		return generateECDSASigningKey(elliptic.P384())
+	case jwt.SigningMethodES512.Alg():
+		return generateECDSASigningKey(elliptic.P521())
+	case jwt.SigningMethodEdDSA.Alg():
+		keyPair, err := GenerateEd25519KeyPair()
+		if err != nil {
+			return nil, err
+		}
+		return keyPair.PrivateKey, nil
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+func generateECDSASigningKey(curve elliptic.Curve) (crypto.Signer, error) {
+	keyPair, err := GenerateECDSAKeyPair(curve)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse %s as private PEM signing key: %v", path, err)
+		return nil, err
+	}
+	return keyPair.PrivateKey, nil
+}
+
+// SigningMethodForKey returns the signing method for a public key
+//
+// RSA keys use RS256, ECDSA keys use ES256, ES384 or ES512 depending on their curve and Ed25519 keys use EdDSA
+func SigningMethodForKey(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("unsupported elliptic curve %q", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
 	}
-	return key, nil
+	return nil, fmt.Errorf("unsupported key type %T", key)
 }
 
 // ParseJwkSet ...
@@ -0,0 +1,35 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package auth
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// staleLockAge is the age after which a lock file left behind by a crashed process is removed
+const staleLockAge = 2 * time.Minute
+
+// lockFile acquires an exclusive lock by creating path, which must not exist
+func lockFile(path string, timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for lock")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
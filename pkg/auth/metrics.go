@@ -0,0 +1,13 @@
+package auth
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// MetricsOptions configures the prometheus metrics of a collector
+//
+// Collectors that are registered with the same registry must differ in their namespace or constant labels.
+type MetricsOptions struct {
+	// Namespace is prepended to the metric names
+	Namespace string
+	// ConstLabels are added to all metrics
+	ConstLabels prometheus.Labels
+}
@@ -0,0 +1,362 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrPasswordTooLong is returned when hashing a password that exceeds the bcrypt limit of 72 bytes
+var ErrPasswordTooLong = errors.New("password exceeds 72 bytes")
+
+// bcryptMaxPasswordLength is the number of bytes bcrypt uses, longer passwords are truncated
+const bcryptMaxPasswordLength = 72
+
+// PasswordHasher hashes and verifies passwords
+type PasswordHasher interface {
+	// Hash returns the hash of a password as a PHC string,
+	// or in the modular crypt format `$2a$cost$...` for bcrypt
+	Hash(password string) (string, error)
+	// Verify checks a password against a hash. Hashes of other algorithms or
+	// weaker parameters are verified as well, in which case rehash reports that the hash
+	// should be replaced using Hash after a successful login.
+	Verify(password, hash string) (ok bool, rehash bool, err error)
+}
+
+// phcHasher is implemented by the built-in password hashers
+type phcHasher interface {
+	PasswordHasher
+	// weaker reports whether the algorithm differs from preferred or any cost parameter is lower
+	weaker(preferred phcHasher) bool
+	// compare checks a password against a hash of this hasher
+	compare(password, hash string) (bool, error)
+}
+
+// Argon2idHasher hashes passwords using argon2id
+//
+// Memory is given in KiB.
+type Argon2idHasher struct {
+	Time       uint32
+	Memory     uint32
+	Threads    uint8
+	KeyLength  uint32
+	SaltLength uint32
+}
+
+// ScryptHasher hashes passwords using scrypt
+//
+// The CPU/memory cost parameter N is 2^LogN.
+type ScryptHasher struct {
+	LogN       uint8
+	R          int
+	P          int
+	KeyLength  int
+	SaltLength int
+}
+
+// BcryptHasher hashes passwords using bcrypt
+//
+// Hash rejects passwords longer than 72 bytes instead of truncating them.
+// Legacy hashes of longer passwords, which HashPassword created from their first 72 bytes,
+// still verify, but only require a rehash if the preferred hasher is not bcrypt.
+type BcryptHasher struct {
+	Cost int
+}
+
+// NewArgon2idHasher returns an argon2id hasher with the parameters recommended by RFC 9106
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Time: 3, Memory: 64 * 1024, Threads: 4, KeyLength: 32, SaltLength: 16}
+}
+
+// NewScryptHasher returns a scrypt hasher with N=2^15, r=8 and p=1
+func NewScryptHasher() *ScryptHasher {
+	return &ScryptHasher{LogN: 15, R: 8, P: 1, KeyLength: 32, SaltLength: 16}
+}
+
+// NewBcryptHasher returns a bcrypt hasher with the same cost as HashPassword
+func NewBcryptHasher() *BcryptHasher {
+	return &BcryptHasher{Cost: bcrypt.DefaultCost + 4}
+}
+
+func randomSalt(size int) ([]byte, error) {
+	salt := make([]byte, size)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+// phcHash is a parsed PHC string of the form `$id[$v=version]$params$salt$hash`
+// ref: https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md
+type phcHash struct {
+	id      string
+	version string
+	params  map[string]uint32
+	salt    []byte
+	hash    []byte
+}
+
+func parsePHC(hash string) (*phcHash, error) {
+	fields := strings.Split(hash, "$")
+	if len(fields) < 5 || fields[0] != "" {
+		return nil, errors.New("invalid PHC string")
+	}
+	parsed := phcHash{id: fields[1], params: make(map[string]uint32)}
+	fields = fields[2:]
+	if strings.HasPrefix(fields[0], "v=") {
+		parsed.version = strings.TrimPrefix(fields[0], "v=")
+		fields = fields[1:]
+	}
+	if len(fields) != 3 {
+		return nil, errors.New("invalid PHC string")
+	}
+	for _, param := range strings.Split(fields[0], ",") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid PHC parameter %q", param)
+		}
+		value, err := strconv.ParseUint(kv[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PHC parameter %q", param)
+		}
+		parsed.params[kv[0]] = uint32(value)
+	}
+	var err error
+	// an empty hash would match the empty key derived with a key length of zero
+	if parsed.salt, err = phcEncoding.DecodeString(fields[1]); err != nil || len(parsed.salt) == 0 {
+		return nil, fmt.Errorf("invalid PHC salt: %q", fields[1])
+	}
+	if parsed.hash, err = phcEncoding.DecodeString(fields[2]); err != nil || len(parsed.hash) == 0 {
+		return nil, fmt.Errorf("invalid PHC hash: %q", fields[2])
+	}
+	return &parsed, nil
+}
+
+// param returns a parameter of the hash after checking that it is within [min, max]
+func (parsed *phcHash) param(name string, min, max uint32) (uint32, error) {
+	value, ok := parsed.params[name]
+	if !ok || value < min || value > max {
+		return 0, fmt.Errorf("invalid %s parameter %s=%d, must be within [%d, %d]", parsed.id, name, value, min, max)
+	}
+	return value, nil
+}
+
+func formatPHC(id, version, params string, salt, hash []byte) string {
+	if version != "" {
+		id += "$v=" + version
+	}
+	return fmt.Sprintf("$%s$%s$%s$%s", id, params, phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(hash))
+}
+
+// identifyHash returns a hasher with the algorithm and parameters of an existing hash
+func identifyHash(hash string) (phcHasher, error) {
+	if strings.HasPrefix(hash, "$2") {
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return nil, err
+		}
+		return &BcryptHasher{Cost: cost}, nil
+	}
+	parsed, err := parsePHC(hash)
+	if err != nil {
+		return nil, err
+	}
+	switch parsed.id {
+	case "argon2id":
+		return argon2idHasherFor(parsed)
+	case "scrypt":
+		return scryptHasherFor(parsed)
+	}
+	return nil, fmt.Errorf("unsupported password hash algorithm %q", parsed.id)
+}
+
+// argon2idHasherFor returns an argon2id hasher with the parameters of a parsed hash
+func argon2idHasherFor(parsed *phcHash) (*Argon2idHasher, error) {
+	if parsed.version != strconv.Itoa(argon2.Version) {
+		return nil, fmt.Errorf("unsupported argon2id version %q", parsed.version)
+	}
+	t, err := parsed.param("t", 1, math.MaxUint32)
+	if err != nil {
+		return nil, err
+	}
+	m, err := parsed.param("m", 1, math.MaxUint32)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parsed.param("p", 1, math.MaxUint8)
+	if err != nil {
+		return nil, err
+	}
+	return &Argon2idHasher{
+		Time:       t,
+		Memory:     m,
+		Threads:    uint8(p),
+		KeyLength:  uint32(len(parsed.hash)),
+		SaltLength: uint32(len(parsed.salt)),
+	}, nil
+}
+
+// scryptHasherFor returns a scrypt hasher with the parameters of a parsed hash
+//
+// scrypt itself rejects r and p with r * p >= 2^30.
+func scryptHasherFor(parsed *phcHash) (*ScryptHasher, error) {
+	ln, err := parsed.param("ln", 1, 30)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parsed.param("r", 1, 1<<30-1)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parsed.param("p", 1, 1<<30-1)
+	if err != nil {
+		return nil, err
+	}
+	return &ScryptHasher{
+		LogN:       uint8(ln),
+		R:          int(r),
+		P:          int(p),
+		KeyLength:  len(parsed.hash),
+		SaltLength: len(parsed.salt),
+	}, nil
+}
+
+// verifyPassword verifies a password against a hash of any supported algorithm
+// and reports whether its algorithm or parameters are weaker than those of the preferred hasher
+//
+// Hashes with stronger parameters are kept, e.g. when calibration chose a lower cost after a restart.
+// No rehash is reported if the preferred hasher is bcrypt and the password too long for it to hash.
+func verifyPassword(preferred phcHasher, password, hash string) (bool, bool, error) {
+	hasher, err := identifyHash(hash)
+	if err != nil {
+		return false, false, err
+	}
+	ok, err := hasher.compare(password, hash)
+	if err != nil || !ok {
+		return false, false, err
+	}
+	rehash := hasher.weaker(preferred) || bcryptTruncated(hasher, password)
+	return true, rehash && !bcryptTruncated(preferred, password), nil
+}
+
+func (hasher *Argon2idHasher) weaker(preferred phcHasher) bool {
+	other, ok := preferred.(*Argon2idHasher)
+	return !ok || hasher.Time < other.Time || hasher.Memory < other.Memory ||
+		hasher.KeyLength < other.KeyLength || hasher.SaltLength < other.SaltLength
+}
+
+// Hash hashes a password using argon2id
+func (hasher *Argon2idHasher) Hash(password string) (string, error) {
+	salt, err := randomSalt(int(hasher.SaltLength))
+	if err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, hasher.Time, hasher.Memory, hasher.Threads, hasher.KeyLength)
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", hasher.Memory, hasher.Time, hasher.Threads)
+	return formatPHC("argon2id", strconv.Itoa(argon2.Version), params, salt, key), nil
+}
+
+func (hasher *Argon2idHasher) compare(password, hash string) (bool, error) {
+	parsed, err := parsePHC(hash)
+	if err != nil {
+		return false, err
+	}
+	if hasher.Time < 1 || hasher.Threads < 1 {
+		return false, errors.New("invalid argon2id parameters")
+	}
+	key := argon2.IDKey([]byte(password), parsed.salt, hasher.Time, hasher.Memory, hasher.Threads, hasher.KeyLength)
+	return subtle.ConstantTimeCompare(key, parsed.hash) == 1, nil
+}
+
+// Verify verifies a password against a hash
+func (hasher *Argon2idHasher) Verify(password, hash string) (bool, bool, error) {
+	return verifyPassword(hasher, password, hash)
+}
+
+func (hasher *ScryptHasher) weaker(preferred phcHasher) bool {
+	other, ok := preferred.(*ScryptHasher)
+	return !ok || hasher.LogN < other.LogN || hasher.R < other.R || hasher.P < other.P ||
+		hasher.KeyLength < other.KeyLength || hasher.SaltLength < other.SaltLength
+}
+
+// Hash hashes a password using scrypt
+func (hasher *ScryptHasher) Hash(password string) (string, error) {
+	salt, err := randomSalt(hasher.SaltLength)
+	if err != nil {
+		return "", err
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<hasher.LogN, hasher.R, hasher.P, hasher.KeyLength)
+	if err != nil {
+		return "", err
+	}
+	params := fmt.Sprintf("ln=%d,r=%d,p=%d", hasher.LogN, hasher.R, hasher.P)
+	return formatPHC("scrypt", "", params, salt, key), nil
+}
+
+func (hasher *ScryptHasher) compare(password, hash string) (bool, error) {
+	parsed, err := parsePHC(hash)
+	if err != nil {
+		return false, err
+	}
+	if hasher.LogN < 1 || hasher.LogN > 30 {
+		return false, errors.New("invalid scrypt parameters")
+	}
+	key, err := scrypt.Key([]byte(password), parsed.salt, 1<<hasher.LogN, hasher.R, hasher.P, hasher.KeyLength)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, parsed.hash) == 1, nil
+}
+
+// Verify verifies a password against a hash
+func (hasher *ScryptHasher) Verify(password, hash string) (bool, bool, error) {
+	return verifyPassword(hasher, password, hash)
+}
+
+// bcryptTruncated reports whether a password is longer than bcrypt can hash
+func bcryptTruncated(hasher phcHasher, password string) bool {
+	_, isBcrypt := hasher.(*BcryptHasher)
+	return isBcrypt && len(password) > bcryptMaxPasswordLength
+}
+
+func (hasher *BcryptHasher) weaker(preferred phcHasher) bool {
+	other, ok := preferred.(*BcryptHasher)
+	return !ok || hasher.Cost < other.Cost
+}
+
+// Hash hashes a password using bcrypt
+func (hasher *BcryptHasher) Hash(password string) (string, error) {
+	if len(password) > bcryptMaxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), hasher.Cost)
+	return string(hash), err
+}
+
+func (hasher *BcryptHasher) compare(password, hash string) (bool, error) {
+	if len(password) > bcryptMaxPasswordLength {
+		// compare like the bcrypt implementation that created legacy hashes
+		password = password[:bcryptMaxPasswordLength]
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Verify verifies a password against a hash
+func (hasher *BcryptHasher) Verify(password, hash string) (bool, bool, error) {
+	return verifyPassword(hasher, password, hash)
+}
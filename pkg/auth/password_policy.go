@@ -0,0 +1,343 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrPasswordPolicy is matched by all *PasswordPolicyError errors
+var ErrPasswordPolicy = errors.New("password does not satisfy the password policy")
+
+// Password policy rules reported in violations
+const (
+	PasswordRuleMinLength        = "min_length"
+	PasswordRuleMaxLength        = "max_length"
+	PasswordRuleLowercase        = "lowercase"
+	PasswordRuleUppercase        = "uppercase"
+	PasswordRuleDigit            = "digit"
+	PasswordRuleSymbol           = "symbol"
+	PasswordRuleCharacterClasses = "character_classes"
+	PasswordRuleEntropy          = "entropy"
+	PasswordRuleBreached         = "breached"
+)
+
+// PasswordViolation describes a password policy rule that a password violates
+type PasswordViolation struct {
+	Rule        string
+	Description string
+}
+
+// PasswordPolicyError lists all violations of a password policy
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+// Error implements the error interface
+func (e *PasswordPolicyError) Error() string {
+	descriptions := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		descriptions[i] = violation.Description
+	}
+	return fmt.Sprintf("%v: %s", ErrPasswordPolicy, strings.Join(descriptions, ", "))
+}
+
+// Is matches ErrPasswordPolicy
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrPasswordPolicy
+}
+
+// BreachedPasswords is a set of passwords known from data breaches
+type BreachedPasswords interface {
+	Contains(password string) (bool, error)
+}
+
+// PasswordPolicy validates passwords before they are hashed
+//
+// Lengths are counted in characters. Zero values disable a rule.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+
+	RequireLowercase bool
+	RequireUppercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// MinCharacterClasses requires characters of at least this many of the
+	// classes lowercase, uppercase, digit and symbol
+	MinCharacterClasses int
+
+	// MinEntropyBits is the minimum estimated entropy, see EstimatePasswordEntropy
+	MinEntropyBits float64
+
+	// Breached rejects passwords known from data breaches
+	Breached BreachedPasswords
+}
+
+// DefaultPasswordPolicy returns a policy following the NIST SP 800-63B recommendations
+// of at least 8 and up to 64 characters without composition rules
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{MinLength: 8, MaxLength: 64}
+}
+
+type characterClasses struct {
+	lower, upper, digit, symbol bool
+}
+
+func classify(password string) characterClasses {
+	var classes characterClasses
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			classes.lower = true
+		case unicode.IsUpper(r):
+			classes.upper = true
+		case unicode.IsDigit(r):
+			classes.digit = true
+		default:
+			classes.symbol = true
+		}
+	}
+	return classes
+}
+
+func (classes characterClasses) count() int {
+	count := 0
+	for _, present := range []bool{classes.lower, classes.upper, classes.digit, classes.symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// EstimatePasswordEntropy returns a rough estimate of the entropy of a password in bits
+//
+// The estimate assumes characters are chosen at random from the character classes
+// that occur in the password. Repeated characters in a row are only counted once.
+func EstimatePasswordEntropy(password string) float64 {
+	classes := classify(password)
+	pool := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{classes.lower, 26}, {classes.upper, 26}, {classes.digit, 10}, {classes.symbol, 33}} {
+		if class.present {
+			pool += class.size
+		}
+	}
+	length := 0
+	var previous rune = -1
+	for _, r := range password {
+		if r != previous {
+			length++
+		}
+		previous = r
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(length) * math.Log2(float64(pool))
+}
+
+func (policy *PasswordPolicy) lengthViolations(password string) []PasswordViolation {
+	var violations []PasswordViolation
+	length := utf8.RuneCountInString(password)
+	if policy.MinLength > 0 && length < policy.MinLength {
+		violations = append(violations, PasswordViolation{
+			Rule:        PasswordRuleMinLength,
+			Description: fmt.Sprintf("must be at least %d characters long", policy.MinLength),
+		})
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		violations = append(violations, PasswordViolation{
+			Rule:        PasswordRuleMaxLength,
+			Description: fmt.Sprintf("must be at most %d characters long", policy.MaxLength),
+		})
+	}
+	return violations
+}
+
+func (policy *PasswordPolicy) compositionViolations(password string) []PasswordViolation {
+	var violations []PasswordViolation
+	classes := classify(password)
+	for _, rule := range []struct {
+		required, present bool
+		rule, description string
+	}{
+		{policy.RequireLowercase, classes.lower, PasswordRuleLowercase, "must contain a lowercase letter"},
+		{policy.RequireUppercase, classes.upper, PasswordRuleUppercase, "must contain an uppercase letter"},
+		{policy.RequireDigit, classes.digit, PasswordRuleDigit, "must contain a digit"},
+		{policy.RequireSymbol, classes.symbol, PasswordRuleSymbol, "must contain a symbol"},
+	} {
+		if rule.required && !rule.present {
+			violations = append(violations, PasswordViolation{Rule: rule.rule, Description: rule.description})
+		}
+	}
+	if policy.MinCharacterClasses > 0 && classes.count() < policy.MinCharacterClasses {
+		violations = append(violations, PasswordViolation{
+			Rule:        PasswordRuleCharacterClasses,
+			Description: fmt.Sprintf("must contain at least %d of lowercase letters, uppercase letters, digits and symbols", policy.MinCharacterClasses),
+		})
+	}
+	if policy.MinEntropyBits > 0 && EstimatePasswordEntropy(password) < policy.MinEntropyBits {
+		violations = append(violations, PasswordViolation{
+			Rule:        PasswordRuleEntropy,
+			Description: "is too easy to guess",
+		})
+	}
+	return violations
+}
+
+// Validate checks a password against the policy
+//
+// If the password violates any rules, a *PasswordPolicyError listing all violations is returned.
+// Other errors occur if the breached passwords could not be checked.
+func (policy *PasswordPolicy) Validate(password string) error {
+	violations := policy.lengthViolations(password)
+	violations = append(violations, policy.compositionViolations(password)...)
+	if policy.Breached != nil {
+		breached, err := policy.Breached.Contains(password)
+		if err != nil {
+			return fmt.Errorf("failed to check breached passwords: %v", err)
+		}
+		if breached {
+			violations = append(violations, PasswordViolation{
+				Rule:        PasswordRuleBreached,
+				Description: "has appeared in a data breach",
+			})
+		}
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func sha1Password(password string) [sha1.Size]byte {
+	return sha1.Sum([]byte(password))
+}
+
+// BreachedPasswordList is an in-memory set of breached passwords stored as SHA-1 hashes
+type BreachedPasswordList struct {
+	hashes map[[sha1.Size]byte]struct{}
+}
+
+// Contains reports whether a password is in the list
+func (list *BreachedPasswordList) Contains(password string) (bool, error) {
+	_, ok := list.hashes[sha1Password(password)]
+	return ok, nil
+}
+
+// readLines calls f for every non-empty line
+func readLines(r io.Reader, f func(line string) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := f(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseSHA1 parses a hex encoded SHA-1 hash followed by an optional `:count`
+func parseSHA1(line string) ([sha1.Size]byte, error) {
+	var hash [sha1.Size]byte
+	line = strings.SplitN(line, ":", 2)[0]
+	if hex.DecodedLen(len(line)) != sha1.Size {
+		return hash, fmt.Errorf("invalid SHA-1 hash %q", line)
+	}
+	_, err := hex.Decode(hash[:], []byte(line))
+	return hash, err
+}
+
+// NewBreachedPasswordList reads newline-delimited plaintext passwords
+func NewBreachedPasswordList(r io.Reader) (*BreachedPasswordList, error) {
+	list := BreachedPasswordList{hashes: make(map[[sha1.Size]byte]struct{})}
+	err := readLines(r, func(line string) error {
+		list.hashes[sha1Password(line)] = struct{}{}
+		return nil
+	})
+	return &list, err
+}
+
+// NewBreachedPasswordHashList reads newline-delimited, hex encoded SHA-1 password hashes
+//
+// Lines may have a `:count` suffix, as in the Pwned Passwords downloads.
+func NewBreachedPasswordHashList(r io.Reader) (*BreachedPasswordList, error) {
+	list := BreachedPasswordList{hashes: make(map[[sha1.Size]byte]struct{})}
+	err := readLines(r, func(line string) error {
+		hash, err := parseSHA1(line)
+		if err != nil {
+			return err
+		}
+		list.hashes[hash] = struct{}{}
+		return nil
+	})
+	return &list, err
+}
+
+// LoadBreachedPasswordList loads a file of newline-delimited plaintext passwords
+func LoadBreachedPasswordList(path string) (*BreachedPasswordList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return NewBreachedPasswordList(file)
+}
+
+// LoadBreachedPasswordHashList loads a file of newline-delimited, hex encoded SHA-1 password hashes
+func LoadBreachedPasswordHashList(path string) (*BreachedPasswordList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return NewBreachedPasswordHashList(file)
+}
+
+// BreachedPasswordRanges looks up breached passwords in a local k-anonymity range directory
+//
+// The directory contains a file for each 5 character SHA-1 hash prefix (e.g. `21BD1`),
+// listing the remaining hash suffixes of breached passwords as `SUFFIX:COUNT`,
+// as served by the Pwned Passwords range API.
+// Only the file of the matching prefix is read, so the directory is never loaded into memory.
+type BreachedPasswordRanges struct {
+	Dir string
+}
+
+// Contains reports whether a password is in the range directory
+func (ranges *BreachedPasswordRanges) Contains(password string) (bool, error) {
+	hash := sha1Password(password)
+	encoded := strings.ToUpper(hex.EncodeToString(hash[:]))
+	prefix, suffix := encoded[:5], encoded[5:]
+	file, err := os.Open(filepath.Join(ranges.Dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.EqualFold(strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)[0], suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func violatedRules(err error) []string {
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return nil
+	}
+	var rules []string
+	for _, violation := range policyErr.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	return rules
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	t.Parallel()
+	policy := PasswordPolicy{
+		MinLength:        8,
+		MaxLength:        16,
+		RequireUppercase: true,
+		RequireDigit:     true,
+		MinEntropyBits:   40,
+	}
+	cases := []struct {
+		password string
+		expected []string
+	}{
+		{"Correct1Horse", nil},
+		{"short", []string{PasswordRuleMinLength, PasswordRuleUppercase, PasswordRuleDigit, PasswordRuleEntropy}},
+		{"Aaaaaaaaaaaaaaaaaaaa1", []string{PasswordRuleMaxLength, PasswordRuleEntropy}},
+		{"lowercase-only", []string{PasswordRuleUppercase, PasswordRuleDigit}},
+		// lengths are counted in characters
+		{"Pässwörter1", nil},
+	}
+	for _, c := range cases {
+		err := policy.Validate(c.password)
+		if rules := violatedRules(err); !reflect.DeepEqual(rules, c.expected) {
+			t.Errorf("%q: expected violations %v but got %v (%v)", c.password, c.expected, rules, err)
+		}
+		if c.expected != nil && !errors.Is(err, ErrPasswordPolicy) {
+			t.Errorf("%q: expected error %v but got %v", c.password, ErrPasswordPolicy, err)
+		}
+	}
+}
+
+func TestPasswordPolicyCharacterClasses(t *testing.T) {
+	t.Parallel()
+	policy := PasswordPolicy{MinCharacterClasses: 3}
+	if err := policy.Validate("abc123"); !reflect.DeepEqual(violatedRules(err), []string{PasswordRuleCharacterClasses}) {
+		t.Errorf("expected character classes violation but got %v", err)
+	}
+	if err := policy.Validate("abc123!"); err != nil {
+		t.Errorf("expected password to be valid: %v", err)
+	}
+}
+
+func TestBreachedPasswords(t *testing.T) {
+	t.Parallel()
+	plain, err := NewBreachedPasswordList(strings.NewReader("password\n123456\n\n"))
+	if err != nil {
+		t.Fatalf("failed to read breached passwords: %v", err)
+	}
+	// SHA-1 of "password" in upper and lower case
+	hashes, err := NewBreachedPasswordHashList(strings.NewReader(
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n7c4a8d09ca3762af61e59520943dc26494f8941b\n",
+	))
+	if err != nil {
+		t.Fatalf("failed to read breached password hashes: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6"), []byte("1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n"), 0o644); err != nil {
+		t.Fatalf("failed to write range file: %v", err)
+	}
+	ranges := &BreachedPasswordRanges{Dir: dir}
+
+	for _, breached := range []BreachedPasswords{plain, hashes, ranges} {
+		policy := PasswordPolicy{Breached: breached}
+		if err := policy.Validate("password"); !reflect.DeepEqual(violatedRules(err), []string{PasswordRuleBreached}) {
+			t.Errorf("%T: expected breached violation but got %v", breached, err)
+		}
+		if err := policy.Validate("correct horse battery staple"); err != nil {
+			t.Errorf("%T: expected password to be valid: %v", breached, err)
+		}
+	}
+
+	if _, err := NewBreachedPasswordHashList(strings.NewReader("not-a-hash\n")); err == nil {
+		t.Error("expected error for invalid hash")
+	}
+}
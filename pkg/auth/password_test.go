@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testHashers() []PasswordHasher {
+	return []PasswordHasher{
+		&Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLength: 32, SaltLength: 16},
+		&ScryptHasher{LogN: 10, R: 8, P: 1, KeyLength: 32, SaltLength: 16},
+		&BcryptHasher{Cost: bcrypt.MinCost},
+	}
+}
+
+func TestPasswordHashers(t *testing.T) {
+	t.Parallel()
+	prefixes := []string{"$argon2id$v=19$m=1024,t=1,p=1$", "$scrypt$ln=10,r=8,p=1$", "$2a$04$"}
+	for i, hasher := range testHashers() {
+		hash, err := hasher.Hash("secret")
+		if err != nil {
+			t.Fatalf("failed to hash password: %v", err)
+		}
+		if !strings.HasPrefix(hash, prefixes[i]) {
+			t.Errorf("expected hash %q to start with %q", hash, prefixes[i])
+		}
+		if ok, rehash, err := hasher.Verify("secret", hash); err != nil || !ok || rehash {
+			t.Errorf("%T: expected password to match without rehash, got ok=%t rehash=%t err=%v", hasher, ok, rehash, err)
+		}
+		if ok, _, err := hasher.Verify("sEcReT", hash); err != nil || ok {
+			t.Errorf("%T: expected wrong password to not match, got ok=%t err=%v", hasher, ok, err)
+		}
+	}
+}
+
+func TestPasswordHasherRequestsRehash(t *testing.T) {
+	t.Parallel()
+	hashers := testHashers()
+	for _, hasher := range hashers {
+		hash, err := hasher.Hash("secret")
+		if err != nil {
+			t.Fatalf("failed to hash password: %v", err)
+		}
+		for _, other := range hashers {
+			if other == hasher {
+				continue
+			}
+			if ok, rehash, err := other.Verify("secret", hash); err != nil || !ok || !rehash {
+				t.Errorf("%T: expected %T hash to match with rehash, got ok=%t rehash=%t err=%v", other, hasher, ok, rehash, err)
+			}
+		}
+	}
+
+	// stronger parameters of the same algorithm
+	weak := &Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLength: 32, SaltLength: 16}
+	strong := &Argon2idHasher{Time: 2, Memory: 2048, Threads: 1, KeyLength: 32, SaltLength: 16}
+	hash, err := weak.Hash("secret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if ok, rehash, err := strong.Verify("secret", hash); err != nil || !ok || !rehash {
+		t.Errorf("expected weak hash to match with rehash, got ok=%t rehash=%t err=%v", ok, rehash, err)
+	}
+
+	// stronger hashes are kept, e.g. if calibration chose a lower cost after a restart
+	hash, err = strong.Hash("secret")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if ok, rehash, err := weak.Verify("secret", hash); err != nil || !ok || rehash {
+		t.Errorf("expected strong hash to match without rehash, got ok=%t rehash=%t err=%v", ok, rehash, err)
+	}
+}
+
+func TestBcryptRejectsLongPasswords(t *testing.T) {
+	t.Parallel()
+	hasher := &BcryptHasher{Cost: bcrypt.MinCost}
+	long := strings.Repeat("a", 100)
+	if _, err := hasher.Hash(long); err != ErrPasswordTooLong {
+		t.Errorf("expected error %v but got %v", ErrPasswordTooLong, err)
+	}
+
+	// legacy hashes were created from the first 72 bytes
+	legacy, err := bcrypt.GenerateFromPassword([]byte(long[:72]), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	for _, hasher := range testHashers() {
+		// bcrypt cannot replace the hash of a long password
+		_, isBcrypt := hasher.(*BcryptHasher)
+		if ok, rehash, err := hasher.Verify(long, string(legacy)); err != nil || !ok || rehash == isBcrypt {
+			t.Errorf("%T: expected long password to match legacy hash with rehash=%t, got ok=%t rehash=%t err=%v", hasher, !isBcrypt, ok, rehash, err)
+		}
+	}
+	stronger := &BcryptHasher{Cost: bcrypt.MinCost + 1}
+	if ok, rehash, err := stronger.Verify(long, string(legacy)); err != nil || !ok || rehash {
+		t.Errorf("expected no rehash of long password by bcrypt, got ok=%t rehash=%t err=%v", ok, rehash, err)
+	}
+	if ok, _, err := hasher.Verify(long[:71]+"b", string(legacy)); err != nil || ok {
+		t.Errorf("expected different password to not match, got ok=%t err=%v", ok, err)
+	}
+}
+
+func TestVerifyRejectsUnknownHashes(t *testing.T) {
+	t.Parallel()
+	hasher := testHashers()[0]
+	for _, hash := range []string{"", "plain", "$md5$abc$def", "$argon2id$v=19$m=1024$abc"} {
+		if ok, _, err := hasher.Verify("secret", hash); err == nil || ok {
+			t.Errorf("expected error for hash %q, got ok=%t", hash, ok)
+		}
+	}
+}
+
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	t.Parallel()
+	hasher := testHashers()[0]
+	salt, hash := "c2FsdHNhbHRzYWx0c2FsdA", "aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"
+	for _, malformed := range []string{
+		// empty salt or hash would derive an empty key, which matches any password
+		"$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$",
+		"$argon2id$v=19$m=1024,t=1,p=1$$" + hash,
+		"$scrypt$ln=10,r=8,p=1$" + salt + "$",
+		"$scrypt$ln=10,r=8,p=1$$" + hash,
+		// parameters out of range
+		"$argon2id$v=19$m=1024,t=1,p=256$" + salt + "$" + hash,
+		"$argon2id$v=19$m=1024,t=0,p=1$" + salt + "$" + hash,
+		"$argon2id$v=19$m=4294967296,t=1,p=1$" + salt + "$" + hash,
+		"$argon2id$v=19$m=1024,p=1$" + salt + "$" + hash,
+		"$argon2id$v=19$m=1024,t=1,p=-1$" + salt + "$" + hash,
+		"$scrypt$ln=31,r=8,p=1$" + salt + "$" + hash,
+		"$scrypt$ln=10,r=0,p=1$" + salt + "$" + hash,
+		"$scrypt$ln=10,r=8,p=1073741824$" + salt + "$" + hash,
+	} {
+		if ok, _, err := hasher.Verify("secret", malformed); err == nil || ok {
+			t.Errorf("expected error for hash %q, got ok=%t", malformed, ok)
+		}
+	}
+}
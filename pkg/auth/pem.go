@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+)
+
+// ErrKeyPassphraseRequired is returned when parsing an encrypted private key without a passphrase
+var ErrKeyPassphraseRequired = errors.New("encrypted private key requires a passphrase")
+
+// errSkipBlock marks PEM blocks that do not contain the requested kind of key
+var errSkipBlock = errors.New("skip PEM block")
+
+// ParseSigningKeyFromPEMData parses a private RSA, ECDSA or Ed25519 signing key from PEM data
+//
+// Keys may be encoded as PKCS#1, SEC 1 or PKCS#8.
+// Use ParseEncryptedSigningKeyFromPEMData for encrypted PKCS#8 keys.
+func ParseSigningKeyFromPEMData(keyData []byte) (crypto.Signer, error) {
+	return ParseEncryptedSigningKeyFromPEMData(keyData, nil)
+}
+
+// ParseEncryptedSigningKeyFromPEMData parses a private RSA, ECDSA or Ed25519 signing key from PEM data
+// and decrypts it using passphrase if it is an encrypted PKCS#8 key
+func ParseEncryptedSigningKeyFromPEMData(keyData, passphrase []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, keyData = pem.Decode(keyData)
+		if block == nil {
+			return nil, errors.New("key must be a PEM encoded RSA, ECDSA or Ed25519 private key")
+		}
+		key, err := parsePrivateKeyBlock(block, passphrase)
+		if errors.Is(err, errSkipBlock) {
+			// e.g. EC PARAMETERS or certificates bundled with the key
+			continue
+		}
+		return key, err
+	}
+}
+
+func parsePrivateKeyBlock(block *pem.Block, passphrase []byte) (crypto.Signer, error) {
+	if _, encrypted := block.Headers["DEK-Info"]; encrypted {
+		return nil, errors.New("legacy encrypted PEM keys are insecure and not supported, convert the key to encrypted PKCS#8")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, ErrKeyPassphraseRequired
+		}
+		key, err = pkcs8.ParsePKCS8PrivateKey(block.Bytes, passphrase)
+	default:
+		return nil, errSkipBlock
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", strings.ToLower(block.Type), err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("expected key type crypto.Signer, but got %T", key)
+	}
+	if _, err := SigningMethodForKey(signer.Public()); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// ParseSigningKeyFromPEMFile parses a private signing key from a PEM file
+func ParseSigningKeyFromPEMFile(path string) (crypto.Signer, error) {
+	return ParseEncryptedSigningKeyFromPEMFile(path, nil)
+}
+
+// ParseEncryptedSigningKeyFromPEMFile parses a private signing key from a PEM file
+// and decrypts it using passphrase if it is an encrypted PKCS#8 key
+func ParseEncryptedSigningKeyFromPEMFile(path string, passphrase []byte) (crypto.Signer, error) {
+	keyData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	key, err := ParseEncryptedSigningKeyFromPEMData(keyData, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s as private PEM signing key: %w", path, err)
+	}
+	return key, nil
+}
+
+// LoadPassphraseFromFile reads a key passphrase from a file
+//
+// A trailing newline is removed.
+func LoadPassphraseFromFile(path string) ([]byte, error) {
+	return loadSecretFile(path)
+}
+
+// PrivateKeyToPKCS8PEM converts a RSA, ECDSA or Ed25519 private key into PKCS#8 PEM format
+//
+// If passphrase is not empty, the key is encrypted with AES-256-CBC using a PBKDF2 derived key.
+func PrivateKeyToPKCS8PEM(key crypto.Signer, passphrase []byte) ([]byte, error) {
+	if _, err := SigningMethodForKey(key.Public()); err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+	der, err := pkcs8.MarshalPrivateKey(key, passphrase, pkcs8.DefaultOpts)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}), nil
+}
+
+// PublicKeyToPEM converts a RSA, ECDSA or Ed25519 public key into SPKI (`PUBLIC KEY`) PEM format
+func PublicKeyToPEM(pub crypto.PublicKey) ([]byte, error) {
+	if _, err := SigningMethodForKey(pub); err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// ParsePublicKeyFromPEMData parses a RSA, ECDSA or Ed25519 public key from PEM data
+//
+// The key may be encoded as SPKI, PKCS#1 or be the public key of the first X.509 certificate.
+func ParsePublicKeyFromPEMData(data []byte) (crypto.PublicKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("key must be a PEM encoded RSA, ECDSA or Ed25519 public key or certificate")
+		}
+		key, err := parsePublicKeyBlock(block)
+		if errors.Is(err, errSkipBlock) {
+			continue
+		}
+		return key, err
+	}
+}
+
+func parsePublicKeyBlock(block *pem.Block) (crypto.PublicKey, error) {
+	var key crypto.PublicKey
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, errSkipBlock
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", strings.ToLower(block.Type), err)
+	}
+	if _, err := SigningMethodForKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ParsePublicKeyFromPEMFile parses a public key from a PEM file
+func ParsePublicKeyFromPEMFile(path string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	key, err := ParsePublicKeyFromPEMData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s as public PEM key: %v", path, err)
+	}
+	return key, nil
+}
+
+// ParseCertificatesFromPEMData parses all X.509 certificates in PEM data in order
+func ParseCertificatesFromPEMData(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificates found")
+	}
+	return certs, nil
+}
+
+// LoadCertificatesFromFile parses all X.509 certificates in a PEM file in order
+func LoadCertificatesFromFile(path string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	certs, err := ParseCertificatesFromPEMData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return certs, nil
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCertificate issues a certificate for key, self-signed if parent is nil
+func testCertificate(t *testing.T, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "mock-issuer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestPKCS8RoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, alg := range []string{"RS256", "ES256", "ES384", "ES512", "EdDSA"} {
+		key, err := GenerateSigningKey(alg)
+		if err != nil {
+			t.Fatalf("failed to generate %s key: %v", alg, err)
+		}
+		for _, passphrase := range [][]byte{nil, []byte("secret")} {
+			keyPEM, err := PrivateKeyToPKCS8PEM(key, passphrase)
+			if err != nil {
+				t.Fatalf("failed to encode %s key: %v", alg, err)
+			}
+			parsed, err := ParseEncryptedSigningKeyFromPEMData(keyPEM, passphrase)
+			if err != nil {
+				t.Fatalf("failed to parse %s key: %v", alg, err)
+			}
+			if !key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(parsed.Public()) {
+				t.Errorf("expected parsed %s key to match", alg)
+			}
+		}
+	}
+}
+
+func TestEncryptedPKCS8RequiresPassphrase(t *testing.T) {
+	t.Parallel()
+	key, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM, err := PrivateKeyToPKCS8PEM(key, []byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	if block, _ := pem.Decode(keyPEM); block.Type != "ENCRYPTED PRIVATE KEY" {
+		t.Errorf("expected encrypted PKCS#8 key but got %q", block.Type)
+	}
+	if _, err := ParseSigningKeyFromPEMData(keyPEM); !errors.Is(err, ErrKeyPassphraseRequired) {
+		t.Errorf("expected error %v but got %v", ErrKeyPassphraseRequired, err)
+	}
+	if _, err := ParseEncryptedSigningKeyFromPEMData(keyPEM, []byte("wrong")); err == nil {
+		t.Errorf("expected wrong passphrase to fail")
+	}
+}
+
+func TestPublicKeyPEM(t *testing.T) {
+	t.Parallel()
+	key, err := GenerateSigningKey("EdDSA")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pubPEM, err := PublicKeyToPEM(key.Public())
+	if err != nil {
+		t.Fatalf("failed to encode public key: %v", err)
+	}
+	cert := testCertificate(t, key, nil, nil)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	for _, data := range [][]byte{pubPEM, certPEM} {
+		pub, err := ParsePublicKeyFromPEMData(data)
+		if err != nil {
+			t.Fatalf("failed to parse public key: %v", err)
+		}
+		if !key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(pub) {
+			t.Errorf("expected parsed public key to match")
+		}
+	}
+}
+
+func TestJwkSetFromCertificateChains(t *testing.T) {
+	t.Parallel()
+	caKey, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	leafKey, err := GenerateSigningKey("ES384")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ca := testCertificate(t, caKey, nil, nil)
+	leaf := testCertificate(t, leafKey, ca, caKey)
+
+	set, err := JwkSetFromCertificateChains([]*x509.Certificate{leaf, ca})
+	if err != nil {
+		t.Fatalf("failed to build JWK set: %v", err)
+	}
+	key, ok := set.Get(0)
+	if !ok || set.Len() != 1 {
+		t.Fatalf("expected a single key but got %d", set.Len())
+	}
+	if kid, _ := Thumbprint(leafKey.Public()); key.KeyID() != kid {
+		t.Errorf("expected key id %q but got %q", kid, key.KeyID())
+	}
+	if len(key.X509CertChain()) != 2 || key.X509CertThumbprint() == "" || key.X509CertThumbprintS256() == "" {
+		t.Errorf("expected x5c, x5t and x5t#S256 members but got %+v", key)
+	}
+	if key.Algorithm() != "ES384" {
+		t.Errorf("expected algorithm ES384 but got %q", key.Algorithm())
+	}
+
+	// the chain must be in order
+	if _, err := JwkSetFromCertificateChains([]*x509.Certificate{ca, leaf}); err == nil {
+		t.Errorf("expected unordered chain to be rejected")
+	}
+}
+
+func TestSetupKeysWithEncryptedKeyAndCertificate(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	key, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM, err := PrivateKeyToPKCS8PEM(key, []byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	cert := testCertificate(t, key, nil, nil)
+	files := map[string][]byte{
+		"key.pem":        keyPEM,
+		"passphrase.txt": []byte("secret\n"),
+		"cert.pem":       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	signer := &Authenticator{ExpiresAfter: time.Minute}
+	if err := signer.SetupKeys(&KeyConfig{
+		KeyFile:           filepath.Join(dir, "key.pem"),
+		KeyPassphraseFile: filepath.Join(dir, "passphrase.txt"),
+		CertificateFile:   filepath.Join(dir, "cert.pem"),
+	}); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	token, err := signer.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	// services that only have the certificate can verify tokens
+	verifier := &Authenticator{ExpiresAfter: time.Minute}
+	if err := verifier.SetupKeys(&KeyConfig{CertificateFile: filepath.Join(dir, "cert.pem")}); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	if verifier.SignKey != nil {
+		t.Errorf("expected no signing key to be generated")
+	}
+	if valid, _, err := verifier.Validate(token, &testClaims{}); err != nil || !valid {
+		t.Errorf("expected token to be valid: %v", err)
+	}
+}
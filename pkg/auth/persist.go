@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// keyFileLockTimeout bounds how long to wait for another process generating the keys
+const keyFileLockTimeout = 30 * time.Second
+
+// writeFileAtomic writes data to a temporary file with 0600 permissions and renames it to path,
+// so that other processes never read a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func fileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// loadOrGenerateKeyFiles loads the signing key from keyFile and its JWK set from jwksFile.
+// Missing files are generated and written with 0600 permissions.
+// The signing key is stored as encrypted PKCS#8 if a passphrase is given.
+//
+// An exclusive lock on `keyFile.lock` ensures that concurrent processes
+// sharing the files generate the keys only once.
+func loadOrGenerateKeyFiles(keyFile, jwksFile, algorithm string, passphrase []byte) (crypto.Signer, jwk.Set, error) {
+	unlock, err := lockFile(keyFile+".lock", keyFileLockTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock %s: %v", keyFile, err)
+	}
+	defer unlock()
+
+	signKey, err := loadOrGenerateKeyFile(keyFile, jwksFile, algorithm, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	if jwksFile == "" {
+		jwkSet, err := ToJwks(signKey.Public())
+		return signKey, jwkSet, err
+	}
+	exists, err := fileExists(jwksFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		jwksJSON, err := ToJwksJSON(signKey.Public())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate JWK set: %v", err)
+		}
+		if err := writeFileAtomic(jwksFile, jwksJSON); err != nil {
+			return nil, nil, fmt.Errorf("failed to write %s: %v", jwksFile, err)
+		}
+	}
+	jwkSet, err := LoadJwkSetFromFile(jwksFile)
+	return signKey, jwkSet, err
+}
+
+func loadOrGenerateKeyFile(keyFile, jwksFile, algorithm string, passphrase []byte) (crypto.Signer, error) {
+	exists, err := fileExists(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return ParseEncryptedSigningKeyFromPEMFile(keyFile, passphrase)
+	}
+	if jwksFile != "" {
+		// a new key would not match the existing JWK set
+		if jwksExists, err := fileExists(jwksFile); err != nil || jwksExists {
+			return nil, fmt.Errorf("%s exists but signing key %s is missing", jwksFile, keyFile)
+		}
+	}
+	signKey, err := GenerateSigningKey(algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %v", err)
+	}
+	var keyPEM []byte
+	if len(passphrase) > 0 {
+		keyPEM, err = PrivateKeyToPKCS8PEM(signKey, passphrase)
+	} else {
+		keyPEM, err = PrivateKeyToPEM(signKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(keyFile, keyPEM); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %v", keyFile, err)
+	}
+	return signKey, nil
+}
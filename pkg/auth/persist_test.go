@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGeneratedKeysArePersisted(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	config := KeyConfig{
+		KeyFile:   filepath.Join(dir, "key.pem"),
+		JwksFile:  filepath.Join(dir, "jwks.json"),
+		Generate:  true,
+		Algorithm: "ES256",
+	}
+
+	first := &Authenticator{ExpiresAfter: time.Minute}
+	if err := first.SetupKeys(&config); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	for _, path := range []string{config.KeyFile, config.JwksFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("expected %s to be written: %v", path, err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("expected %s to have permissions 0600 but got %o", path, perm)
+		}
+	}
+
+	// a restarted service reuses the keys and accepts previously issued tokens
+	token, err := first.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	second := &Authenticator{ExpiresAfter: time.Minute}
+	if err := second.SetupKeys(&config); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	if valid, _, err := second.Validate(token, &testClaims{}); err != nil || !valid {
+		t.Errorf("expected token to be valid after restart: %v", err)
+	}
+}
+
+func TestConcurrentKeyGenerationCreatesOneKey(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	config := KeyConfig{
+		KeyFile:   filepath.Join(dir, "key.pem"),
+		JwksFile:  filepath.Join(dir, "jwks.json"),
+		Generate:  true,
+		Algorithm: "ES256",
+	}
+
+	authenticators := make([]*Authenticator, 4)
+	var wg sync.WaitGroup
+	for i := range authenticators {
+		authenticators[i] = &Authenticator{ExpiresAfter: time.Minute}
+		wg.Add(1)
+		go func(auth *Authenticator) {
+			defer wg.Done()
+			if err := auth.SetupKeys(&config); err != nil {
+				t.Errorf("failed to setup keys: %v", err)
+			}
+		}(authenticators[i])
+	}
+	wg.Wait()
+
+	token, err := authenticators[0].SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	for _, auth := range authenticators[1:] {
+		if valid, _, err := auth.Validate(token, &testClaims{}); err != nil || !valid {
+			t.Errorf("expected all replicas to share the key: %v", err)
+		}
+	}
+}
+
+func TestGenerateRefusesToReplaceJwks(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	config := KeyConfig{
+		KeyFile:  filepath.Join(dir, "key.pem"),
+		JwksFile: filepath.Join(dir, "jwks.json"),
+		Generate: true,
+	}
+	if err := os.WriteFile(config.JwksFile, []byte(`{"keys":[]}`), 0o600); err != nil {
+		t.Fatalf("failed to write jwks: %v", err)
+	}
+	if err := new(Authenticator).SetupKeys(&config); err == nil {
+		t.Error("expected error when the signing key of an existing JWK set is missing")
+	}
+}
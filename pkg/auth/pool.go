@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrPasswordHashingOverloaded is returned when the password hashing queue is full
+var ErrPasswordHashingOverloaded = errors.New("too many concurrent password hashing requests")
+
+// PasswordHashingPool bounds the number of concurrent password hashing operations
+//
+// At most `workers` passwords are hashed concurrently and up to `queueSize` requests wait for a worker.
+// Further requests fail immediately with ErrPasswordHashingOverloaded instead of exhausting the CPU.
+//
+// The pool implements prometheus.Collector and can be registered to export queue metrics.
+type PasswordHashingPool struct {
+	Hasher PasswordHasher
+
+	workers  chan struct{}
+	admitted chan struct{}
+
+	queued   prometheus.Gauge
+	active   prometheus.Gauge
+	rejected prometheus.Counter
+	duration prometheus.Histogram
+}
+
+// NewPasswordHashingPool creates a new password hashing pool
+func NewPasswordHashingPool(hasher PasswordHasher, workers, queueSize int) *PasswordHashingPool {
+	return NewPasswordHashingPoolWithMetrics(hasher, workers, queueSize, MetricsOptions{})
+}
+
+// NewPasswordHashingPoolWithMetrics creates a new password hashing pool whose metrics use the given namespace and labels
+func NewPasswordHashingPoolWithMetrics(hasher PasswordHasher, workers, queueSize int, metrics MetricsOptions) *PasswordHashingPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &PasswordHashingPool{
+		Hasher:   hasher,
+		workers:  make(chan struct{}, workers),
+		admitted: make(chan struct{}, workers+queueSize),
+		queued: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   metrics.Namespace,
+			Name:        "password_hashing_queue_depth",
+			Help:        "Number of password hashing requests waiting for a worker.",
+			ConstLabels: metrics.ConstLabels,
+		}),
+		active: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   metrics.Namespace,
+			Name:        "password_hashing_active",
+			Help:        "Number of passwords being hashed.",
+			ConstLabels: metrics.ConstLabels,
+		}),
+		rejected: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   metrics.Namespace,
+			Name:        "password_hashing_rejected_total",
+			Help:        "Number of password hashing requests rejected because the queue was full.",
+			ConstLabels: metrics.ConstLabels,
+		}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   metrics.Namespace,
+			Name:        "password_hashing_duration_seconds",
+			Help:        "Time spent hashing a password.",
+			ConstLabels: metrics.ConstLabels,
+			Buckets:     prometheus.ExponentialBuckets(0.005, 2, 12),
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (pool *PasswordHashingPool) Describe(ch chan<- *prometheus.Desc) {
+	pool.queued.Describe(ch)
+	pool.active.Describe(ch)
+	pool.rejected.Describe(ch)
+	pool.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (pool *PasswordHashingPool) Collect(ch chan<- prometheus.Metric) {
+	pool.queued.Collect(ch)
+	pool.active.Collect(ch)
+	pool.rejected.Collect(ch)
+	pool.duration.Collect(ch)
+}
+
+// acquire waits for a free worker and returns a function to release it
+func (pool *PasswordHashingPool) acquire(ctx context.Context) (func(), error) {
+	select {
+	case pool.admitted <- struct{}{}:
+	default:
+		pool.rejected.Inc()
+		return nil, ErrPasswordHashingOverloaded
+	}
+	pool.queued.Inc()
+	select {
+	case pool.workers <- struct{}{}:
+		pool.queued.Dec()
+	case <-ctx.Done():
+		pool.queued.Dec()
+		<-pool.admitted
+		return nil, ctx.Err()
+	}
+	pool.active.Inc()
+	start := time.Now()
+	return func() {
+		pool.duration.Observe(time.Since(start).Seconds())
+		pool.active.Dec()
+		<-pool.workers
+		<-pool.admitted
+	}, nil
+}
+
+// Hash hashes a password once a worker is available
+func (pool *PasswordHashingPool) Hash(ctx context.Context, password string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	release, err := pool.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return pool.Hasher.Hash(password)
+}
+
+// Verify verifies a password once a worker is available
+func (pool *PasswordHashingPool) Verify(ctx context.Context, password, hash string) (bool, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, false, err
+	}
+	release, err := pool.acquire(ctx)
+	if err != nil {
+		return false, false, err
+	}
+	defer release()
+	return pool.Hasher.Verify(password, hash)
+}
+
+// WithContext returns a PasswordHasher that hashes and verifies passwords using the pool
+func (pool *PasswordHashingPool) WithContext(ctx context.Context) PasswordHasher {
+	return &poolHasher{pool: pool, ctx: ctx}
+}
+
+type poolHasher struct {
+	pool *PasswordHashingPool
+	ctx  context.Context
+}
+
+func (hasher *poolHasher) Hash(password string) (string, error) {
+	return hasher.pool.Hash(hasher.ctx, password)
+}
+
+func (hasher *poolHasher) Verify(password, hash string) (bool, bool, error) {
+	return hasher.pool.Verify(hasher.ctx, password, hash)
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// blockingHasher blocks hashing until released
+type blockingHasher struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (hasher *blockingHasher) Hash(password string) (string, error) {
+	hasher.started <- struct{}{}
+	<-hasher.release
+	return password, nil
+}
+
+func (hasher *blockingHasher) Verify(password, hash string) (bool, bool, error) {
+	_, err := hasher.Hash(password)
+	return password == hash, false, err
+}
+
+func TestPasswordHashingPoolRejectsWhenFull(t *testing.T) {
+	t.Parallel()
+	hasher := &blockingHasher{started: make(chan struct{}), release: make(chan struct{})}
+	pool := NewPasswordHashingPool(hasher, 1, 1)
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() {
+		_, err := pool.Hash(ctx, "secret")
+		done <- err
+	}()
+	<-hasher.started
+
+	// the second request waits in the queue until it is cancelled
+	queuedCtx, cancel := context.WithCancel(ctx)
+	queued := make(chan error)
+	go func() {
+		_, _, err := pool.Verify(queuedCtx, "secret", "secret")
+		queued <- err
+	}()
+	for testutil.ToFloat64(pool.queued) < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the third request is rejected immediately
+	if _, err := pool.Hash(ctx, "secret"); !errors.Is(err, ErrPasswordHashingOverloaded) {
+		t.Errorf("expected error %v but got %v", ErrPasswordHashingOverloaded, err)
+	}
+	if rejected := testutil.ToFloat64(pool.rejected); rejected != 1 {
+		t.Errorf("expected 1 rejected request but got %v", rejected)
+	}
+
+	cancel()
+	if err := <-queued; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %v but got %v", context.Canceled, err)
+	}
+	close(hasher.release)
+	if err := <-done; err != nil {
+		t.Errorf("failed to hash password: %v", err)
+	}
+
+	// capacity is available again
+	go func() { <-hasher.started }()
+	if _, err := pool.Hash(ctx, "secret"); err != nil {
+		t.Errorf("failed to hash password: %v", err)
+	}
+	if queueDepth := testutil.ToFloat64(pool.queued); queueDepth != 0 {
+		t.Errorf("expected empty queue but got %v", queueDepth)
+	}
+}
+
+func TestPasswordHashingPoolsRegisterWithDistinctLabels(t *testing.T) {
+	t.Parallel()
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{"users", "api-keys"} {
+		pool := NewPasswordHashingPoolWithMetrics(NewBcryptHasher(), 1, 0, MetricsOptions{
+			Namespace:   "service",
+			ConstLabels: prometheus.Labels{"pool": name},
+		})
+		if err := registry.Register(pool); err != nil {
+			t.Errorf("failed to register pool %q: %v", name, err)
+		}
+	}
+	if n, err := testutil.GatherAndCount(registry, "service_password_hashing_active"); err != nil || n != 2 {
+		t.Errorf("expected metrics of 2 pools but got %d: %v", n, err)
+	}
+}
+
+func TestCalibratePasswordHasher(t *testing.T) {
+	t.Parallel()
+	base := &BcryptHasher{Cost: bcrypt.MinCost}
+
+	// a target that cannot be met returns the base hasher
+	calibrated, err := CalibratePasswordHasher(base, 0)
+	if err != nil {
+		t.Fatalf("failed to calibrate: %v", err)
+	}
+	if cost := calibrated.(*BcryptHasher).Cost; cost != bcrypt.MinCost {
+		t.Errorf("expected cost %d but got %d", bcrypt.MinCost, cost)
+	}
+
+	elapsed, err := measure(&BcryptHasher{Cost: bcrypt.MinCost + 4})
+	if err != nil {
+		t.Fatalf("failed to measure: %v", err)
+	}
+	calibrated, err = CalibratePasswordHasher(base, elapsed)
+	if err != nil {
+		t.Fatalf("failed to calibrate: %v", err)
+	}
+	if cost := calibrated.(*BcryptHasher).Cost; cost < bcrypt.MinCost+1 {
+		t.Errorf("expected cost to be raised but got %d", cost)
+	}
+
+	if _, err := CalibratePasswordHasher(&blockingHasher{}, time.Second); err == nil {
+		t.Error("expected error for unsupported hasher")
+	}
+}
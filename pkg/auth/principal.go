@@ -0,0 +1,45 @@
+package auth
+
+import "context"
+
+// Authentication methods of a principal
+const (
+	AuthMethodJWT         = "jwt"
+	AuthMethodAPIKey      = "api_key"
+	AuthMethodCertificate = "certificate"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject identifies the caller, e.g. the `sub` claim of a token or the owner of an API key
+	Subject string
+	// Scopes granted to the caller
+	Scopes []string
+	// Method is the authentication method, e.g. AuthMethodAPIKey
+	Method string
+	// Claims are the validated token claims if the caller authenticated using a token
+	Claims Claims
+}
+
+// GetScopes returns the scopes of the principal
+func (principal *Principal) GetScopes() []string {
+	return principal.Scopes
+}
+
+// HasScope reports whether the principal has been granted a scope
+func (principal *Principal) HasScope(scope string) bool {
+	return contains(principal.Scopes, scope)
+}
+
+type principalKey struct{}
+
+// WithPrincipal injects the authenticated principal into context
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext extracts the authenticated principal from context
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidRecoveryCode means the recovery code is wrong or has already been used
+var ErrInvalidRecoveryCode = errors.New("recovery code is invalid")
+
+// DefaultRecoveryCodes is the number of recovery codes that are usually generated
+const DefaultRecoveryCodes = 10
+
+// recoveryCodeSize is the number of random bytes of a recovery code, which are encoded as 16 characters
+const recoveryCodeSize = 10
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes generates single-use recovery codes that replace a second factor
+// when the user lost access to it, e.g. to their authenticator app.
+//
+// The codes are shown to the user once, only their hashes must be stored.
+// Codes have 80 bits of entropy and are formatted as `xxxx-xxxx-xxxx-xxxx`.
+func GenerateRecoveryCodes(hasher PasswordHasher, n int) (codes []string, hashes []string, err error) {
+	for i := 0; i < n; i++ {
+		random := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+		code := recoveryCodeEncoding.EncodeToString(random)
+		hash, err := hasher.Hash(code)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[0:4]+"-"+code[4:8]+"-"+code[8:12]+"-"+code[12:16])
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode removes separators and whitespace that users may enter
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
+
+// UseRecoveryCode verifies a recovery code against the stored hashes
+//
+// It returns the hashes without the hash of the code, which must be stored to prevent the code from being used again.
+// Returns ErrInvalidRecoveryCode if no hash matches.
+func UseRecoveryCode(hasher PasswordHasher, code string, hashes []string) ([]string, error) {
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeEncoding.EncodedLen(recoveryCodeSize) {
+		return hashes, ErrInvalidRecoveryCode
+	}
+	for i, hash := range hashes {
+		ok, _, err := hasher.Verify(code, hash)
+		if err != nil {
+			return hashes, err
+		}
+		if ok {
+			remaining := append([]string{}, hashes[:i]...)
+			return append(remaining, hashes[i+1:]...), nil
+		}
+	}
+	return hashes, ErrInvalidRecoveryCode
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// DefaultRefreshExpiresAfter is the lifetime of refresh tokens if not configured
+const DefaultRefreshExpiresAfter = 30 * 24 * time.Hour
+
+// RefreshToken is a refresh token as persisted by a RefreshTokenStore
+//
+// The opaque token itself is never stored, only its hash.
+type RefreshToken struct {
+	// Hash is the hex encoded SHA-256 hash of the opaque token
+	Hash string
+	// FamilyID identifies all refresh tokens that descend from the same login
+	FamilyID string
+	// Claims are the JSON encoded claims used to sign new access tokens
+	Claims    []byte
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// Used is set once the token has been exchanged
+	Used bool
+	// Revoked is set when the token family has been revoked
+	Revoked bool
+}
+
+// RefreshTokenStore persists refresh tokens
+type RefreshTokenStore interface {
+	// Create stores a new refresh token, whose IssuedAt is the time of the clock of the Authenticator
+	Create(ctx context.Context, token *RefreshToken) error
+	// Use marks the refresh token with the given hash as used and returns it as it was before.
+	// Marking must be atomic, so that concurrent exchanges of the same token are detected as reuse.
+	// Returns ErrRefreshTokenInvalid if there is no such token.
+	Use(ctx context.Context, hash string) (*RefreshToken, error)
+	// RevokeFamily revokes all refresh tokens of a family
+	RevokeFamily(ctx context.Context, familyID string) error
+}
+
+// TokenPair is an access token together with the refresh token to renew it
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// MemoryRefreshTokenStore is an in-memory RefreshTokenStore
+type MemoryRefreshTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*RefreshToken
+}
+
+// NewMemoryRefreshTokenStore creates a new in-memory refresh token store
+func NewMemoryRefreshTokenStore() *MemoryRefreshTokenStore {
+	return &MemoryRefreshTokenStore{
+		tokens: make(map[string]*RefreshToken),
+	}
+}
+
+// Create stores a new refresh token and evicts tokens that expired before it was issued
+func (store *MemoryRefreshTokenStore) Create(ctx context.Context, token *RefreshToken) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for hash, stored := range store.tokens {
+		if token.IssuedAt.After(stored.ExpiresAt) {
+			delete(store.tokens, hash)
+		}
+	}
+	if _, ok := store.tokens[token.Hash]; ok {
+		return fmt.Errorf("refresh token %q already exists", token.Hash)
+	}
+	stored := *token
+	store.tokens[token.Hash] = &stored
+	return nil
+}
+
+// Use marks a refresh token as used
+func (store *MemoryRefreshTokenStore) Use(ctx context.Context, hash string) (*RefreshToken, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	stored, ok := store.tokens[hash]
+	if !ok {
+		return nil, ErrRefreshTokenInvalid
+	}
+	token := *stored
+	stored.Used = true
+	return &token, nil
+}
+
+// RevokeFamily revokes all refresh tokens of a family
+func (store *MemoryRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, stored := range store.tokens {
+		if stored.FamilyID == familyID {
+			stored.Revoked = true
+		}
+	}
+	return nil
+}
+
+// randomToken returns a random, URL safe token
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashRefreshToken returns the hash of a refresh token under which it is stored
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func (auth *Authenticator) refreshTokenStore() (RefreshTokenStore, error) {
+	if auth.RefreshTokens == nil {
+		return nil, errors.New("no refresh token store configured")
+	}
+	return auth.RefreshTokens, nil
+}
+
+func (auth *Authenticator) refreshExpiresAfter() time.Duration {
+	if auth.RefreshExpiresAfter > 0 {
+		return auth.RefreshExpiresAfter
+	}
+	return DefaultRefreshExpiresAfter
+}
+
+// encodeRefreshClaims encodes signed claims without the registered claims that are set anew on every refresh
+func encodeRefreshClaims(claims Claims) ([]byte, error) {
+	reg := claims.GetRegisteredClaims()
+	signed := *reg
+	reg.ExpiresAt, reg.NotBefore, reg.IssuedAt, reg.ID = nil, nil, nil, ""
+	defer func() {
+		*reg = signed
+	}()
+	return json.Marshal(claims)
+}
+
+// issueTokenPair signs the claims and creates a new refresh token in the given family
+func (auth *Authenticator) issueTokenPair(ctx context.Context, store RefreshTokenStore, familyID string, claims Claims, opts ...SignOption) (*TokenPair, error) {
+	accessToken, err := auth.Sign(claims, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// the claims are stored as signed, including the registered claims set using options
+	encoded, err := encodeRefreshClaims(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode claims: %v", err)
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := auth.now()
+	stored := RefreshToken{
+		Hash:      hashRefreshToken(refreshToken),
+		FamilyID:  familyID,
+		Claims:    encoded,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(auth.refreshExpiresAfter()),
+	}
+	if err := store.Create(ctx, &stored); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %v", err)
+	}
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  claims.GetRegisteredClaims().ExpiresAt.Time,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// Login signs the claims and returns an access token together with a new refresh token
+//
+// The claims are persisted with the refresh token as signed, including the `sub` and `aud` claims.
+// Only the exp, nbf, iat and jti claims are set anew whenever the refresh token is exchanged.
+func (auth *Authenticator) Login(ctx context.Context, claims Claims, opts ...SignOption) (*TokenPair, error) {
+	store, err := auth.refreshTokenStore()
+	if err != nil {
+		return nil, err
+	}
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return auth.issueTokenPair(ctx, store, familyID, claims, opts...)
+}
+
+// Refresh exchanges a refresh token for a new token pair
+//
+// The claims of the login are decoded into claims and signed again.
+// Every refresh token can only be used once. If a refresh token is reused,
+// all refresh tokens of the family are revoked and ErrRefreshTokenReused is returned.
+// If the subject has been revoked since the refresh token was issued, see RevokeSubject,
+// the family is revoked as well and ErrRefreshTokenInvalid is returned.
+func (auth *Authenticator) Refresh(ctx context.Context, refreshToken string, claims Claims, opts ...SignOption) (*TokenPair, error) {
+	store, err := auth.refreshTokenStore()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := store.Use(ctx, hashRefreshToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenInvalid) {
+		return nil, &ValidationError{Reason: ErrRefreshTokenInvalid}
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case stored.Revoked:
+		return nil, &ValidationError{Reason: ErrRefreshTokenInvalid, Err: errors.New("refresh token has been revoked")}
+	case stored.Used:
+		if err := store.RevokeFamily(ctx, stored.FamilyID); err != nil {
+			return nil, fmt.Errorf("failed to revoke refresh token family: %v", err)
+		}
+		return nil, &ValidationError{Reason: ErrRefreshTokenReused}
+	case auth.now().After(stored.ExpiresAt):
+		return nil, &ValidationError{Reason: ErrRefreshTokenInvalid, Err: errors.New("refresh token is expired")}
+	}
+	if err := json.Unmarshal(stored.Claims, claims); err != nil {
+		return nil, fmt.Errorf("failed to decode claims: %v", err)
+	}
+	if err := auth.checkRefreshRevoked(ctx, store, stored, claims); err != nil {
+		return nil, err
+	}
+	return auth.issueTokenPair(ctx, store, stored.FamilyID, claims, opts...)
+}
+
+// checkRefreshRevoked revokes the family of a refresh token whose subject has been revoked since it was issued
+func (auth *Authenticator) checkRefreshRevoked(ctx context.Context, store RefreshTokenStore, stored *RefreshToken, claims Claims) error {
+	if auth.Revocations == nil {
+		return nil
+	}
+	subject := claims.GetRegisteredClaims().Subject
+	if subject == "" {
+		return nil
+	}
+	revoked, err := auth.Revocations.IsRevoked(ctx, &jwt.RegisteredClaims{
+		Subject:  subject,
+		IssuedAt: jwt.NewNumericDate(stored.IssuedAt),
+	}, auth.now())
+	if err != nil {
+		return fmt.Errorf("failed to check revocation: %v", err)
+	}
+	if !revoked {
+		return nil
+	}
+	if err := store.RevokeFamily(ctx, stored.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %v", err)
+	}
+	return &ValidationError{Reason: ErrRefreshTokenInvalid, Err: errors.New("subject has been revoked")}
+}
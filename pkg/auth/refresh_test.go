@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	test := new(test).setup(t)
+	test.authenticator.RefreshTokens = NewMemoryRefreshTokenStore()
+	ctx := context.Background()
+
+	pair, err := test.authenticator.Login(ctx, &testClaims{UserID: "123"}, WithSubject("user-123"))
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	if _, err := ValidateAs[*testClaims](test.authenticator, pair.AccessToken); err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+
+	claims := &testClaims{}
+	refreshed, err := test.authenticator.Refresh(ctx, pair.RefreshToken, claims, WithSubject("user-123"))
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	if claims.UserID != "123" {
+		t.Errorf("expected user id %q but got %q", "123", claims.UserID)
+	}
+	if refreshed.RefreshToken == pair.RefreshToken {
+		t.Error("expected refresh token to be rotated")
+	}
+	validated, err := ValidateAs[*testClaims](test.authenticator, refreshed.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate refreshed access token: %v", err)
+	}
+	if validated.UserID != "123" || validated.Subject != "user-123" {
+		t.Errorf("unexpected refreshed claims %+v", validated)
+	}
+	if ttl := refreshed.RefreshExpiresAt.Sub(time.Now()); ttl < DefaultRefreshExpiresAfter-time.Minute {
+		t.Errorf("unexpected refresh token lifetime %v", ttl)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	test := new(test).setup(t)
+	test.authenticator.RefreshTokens = NewMemoryRefreshTokenStore()
+	ctx := context.Background()
+
+	pair, err := test.authenticator.Login(ctx, &testClaims{UserID: "123"})
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	refreshed, err := test.authenticator.Refresh(ctx, pair.RefreshToken, &testClaims{})
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+
+	// reusing the first refresh token is detected
+	_, err = test.authenticator.Refresh(ctx, pair.RefreshToken, &testClaims{})
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected error %v but got %v", ErrRefreshTokenReused, err)
+	}
+
+	// the latest refresh token of the family is revoked as well
+	_, err = test.authenticator.Refresh(ctx, refreshed.RefreshToken, &testClaims{})
+	if !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("expected error %v but got %v", ErrRefreshTokenInvalid, err)
+	}
+
+	// other families are not affected
+	other, err := test.authenticator.Login(ctx, &testClaims{UserID: "456"})
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	if _, err := test.authenticator.Refresh(ctx, other.RefreshToken, &testClaims{}); err != nil {
+		t.Errorf("failed to refresh: %v", err)
+	}
+}
+
+func TestRefreshRejectsUnknownAndExpiredTokens(t *testing.T) {
+	test := new(test).setup(t)
+	test.authenticator.RefreshTokens = NewMemoryRefreshTokenStore()
+	test.authenticator.RefreshExpiresAfter = time.Nanosecond
+	ctx := context.Background()
+
+	if _, err := test.authenticator.Refresh(ctx, "unknown", &testClaims{}); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("expected error %v but got %v", ErrRefreshTokenInvalid, err)
+	}
+	pair, err := test.authenticator.Login(ctx, &testClaims{})
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	if _, err := test.authenticator.Refresh(ctx, pair.RefreshToken, &testClaims{}); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("expected error %v but got %v", ErrRefreshTokenInvalid, err)
+	}
+}
+
+func TestRefreshKeepsRegisteredClaims(t *testing.T) {
+	test := new(test).setup(t)
+	test.authenticator.RefreshTokens = NewMemoryRefreshTokenStore()
+	ctx := context.Background()
+
+	pair, err := test.authenticator.Login(ctx, &testClaims{UserID: "123"}, WithSubject("user-123"), WithAudience("other-service"))
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	login, err := ValidateAs[*testClaims](test.authenticator, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate access token: %v", err)
+	}
+
+	// no options are passed when refreshing
+	refreshed, err := test.authenticator.Refresh(ctx, pair.RefreshToken, &testClaims{})
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	validated, err := ValidateAs[*testClaims](test.authenticator, refreshed.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate refreshed access token: %v", err)
+	}
+	if validated.Subject != "user-123" {
+		t.Errorf("expected subject %q but got %q", "user-123", validated.Subject)
+	}
+	if !reflect.DeepEqual(validated.Audience, login.Audience) {
+		t.Errorf("expected audience %v but got %v", login.Audience, validated.Audience)
+	}
+	if validated.ID == login.ID {
+		t.Errorf("expected refreshed access token to have a new id, got %q", validated.ID)
+	}
+}
+
+func TestRefreshFailsForRevokedSubject(t *testing.T) {
+	test := new(test).setup(t)
+	test.authenticator.RefreshTokens = NewMemoryRefreshTokenStore()
+	test.authenticator.Revocations = NewMemoryRevocationStore()
+	ctx := context.Background()
+
+	pair, err := test.authenticator.Login(ctx, &testClaims{UserID: "123"}, WithSubject("user-123"))
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+	if err := test.authenticator.RevokeSubject(ctx, "user-123", time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to revoke subject: %v", err)
+	}
+	if _, err := test.authenticator.Refresh(ctx, pair.RefreshToken, &testClaims{}); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("expected error %v but got %v", ErrRefreshTokenInvalid, err)
+	}
+}
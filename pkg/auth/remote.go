@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// DefaultUnknownKeyRefreshInterval is the default minimum interval between
+// refreshes of a remote JWK set that are caused by unknown key ids
+const DefaultUnknownKeyRefreshInterval = 1 * time.Minute
+
+// DefaultRemoteJwkSetTimeout is the default timeout of requests to fetch a remote JWK set
+const DefaultRemoteJwkSetTimeout = 10 * time.Second
+
+// RemoteJwkSetOptions configures a remote JWK set
+type RemoteJwkSetOptions struct {
+	// HTTPClient is used to fetch the JWK set, defaults to a client with DefaultRemoteJwkSetTimeout
+	HTTPClient *http.Client
+	// MinRefreshInterval is the minimum interval between background refreshes.
+	// The interval is taken from the Cache-Control or Expires headers of the response if larger.
+	MinRefreshInterval time.Duration
+	// UnknownKeyRefreshInterval is the minimum interval between refreshes caused by unknown key ids
+	// and between attempts to fetch the JWK set if it has never been fetched successfully
+	UnknownKeyRefreshInterval time.Duration
+}
+
+// RemoteJwkSet is a JWK set fetched from a URL that is cached and refreshed in the background.
+//
+// If a refresh fails, the last JWK set that was fetched successfully continues to be used.
+type RemoteJwkSet struct {
+	URL string
+
+	refresh                   *jwk.AutoRefresh
+	cancel                    context.CancelFunc
+	unknownKeyRefreshInterval time.Duration
+
+	mu          sync.Mutex
+	fetched     bool
+	lastRefresh time.Time
+}
+
+// NewRemoteJwkSet creates a new remote JWK set that is refreshed until the context is cancelled or Close is called.
+//
+// The JWK set is fetched lazily on the first lookup.
+func NewRemoteJwkSet(ctx context.Context, url string, options *RemoteJwkSetOptions) *RemoteJwkSet {
+	if options == nil {
+		options = &RemoteJwkSetOptions{}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	refresh := jwk.NewAutoRefresh(ctx)
+
+	client := options.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultRemoteJwkSetTimeout}
+	}
+	refreshOptions := []jwk.AutoRefreshOption{jwk.WithHTTPClient(client)}
+	if options.MinRefreshInterval > 0 {
+		refreshOptions = append(refreshOptions, jwk.WithMinRefreshInterval(options.MinRefreshInterval))
+	}
+	refresh.Configure(url, refreshOptions...)
+
+	unknownKeyRefreshInterval := options.UnknownKeyRefreshInterval
+	if unknownKeyRefreshInterval <= 0 {
+		unknownKeyRefreshInterval = DefaultUnknownKeyRefreshInterval
+	}
+	return &RemoteJwkSet{
+		URL:                       url,
+		refresh:                   refresh,
+		cancel:                    cancel,
+		unknownKeyRefreshInterval: unknownKeyRefreshInterval,
+	}
+}
+
+// Fetch returns the cached JWK set or fetches it if it has never been fetched
+func (remote *RemoteJwkSet) Fetch(ctx context.Context) (jwk.Set, error) {
+	return remote.refresh.Fetch(ctx, remote.URL)
+}
+
+// allowRefresh rate limits refreshes caused by unknown key ids
+func (remote *RemoteJwkSet) allowRefresh() bool {
+	remote.mu.Lock()
+	defer remote.mu.Unlock()
+	now := time.Now()
+	if !remote.lastRefresh.IsZero() && now.Sub(remote.lastRefresh) < remote.unknownKeyRefreshInterval {
+		return false
+	}
+	remote.lastRefresh = now
+	return true
+}
+
+// cached reports whether the JWK set has been fetched successfully and is cached
+func (remote *RemoteJwkSet) cached() bool {
+	remote.mu.Lock()
+	defer remote.mu.Unlock()
+	return remote.fetched
+}
+
+// refreshed records a successful refresh
+func (remote *RemoteJwkSet) refreshed() {
+	remote.mu.Lock()
+	defer remote.mu.Unlock()
+	if !remote.fetched {
+		// the initial fetch does not delay the refresh for the first unknown key id
+		remote.fetched = true
+		remote.lastRefresh = time.Time{}
+	}
+}
+
+// LookupKeyID finds the key with the given id.
+//
+// If the key id is unknown, the JWK set is refreshed, at most once per UnknownKeyRefreshInterval.
+// The same limit applies to fetching the JWK set until it has been fetched successfully.
+// The JWK set is fetched using ctx, e.g. the context of the request.
+func (remote *RemoteJwkSet) LookupKeyID(ctx context.Context, kid string) (jwk.Key, bool) {
+	if remote.cached() {
+		// served from the cache without a request
+		if set, err := remote.Fetch(ctx); err == nil {
+			if key, ok := set.LookupKeyID(kid); ok {
+				return key, true
+			}
+		}
+	}
+	if !remote.allowRefresh() {
+		return nil, false
+	}
+	set, err := remote.refresh.Refresh(ctx, remote.URL)
+	if err != nil {
+		return nil, false
+	}
+	remote.refreshed()
+	return set.LookupKeyID(kid)
+}
+
+// Close stops refreshing the JWK set
+func (remote *RemoteJwkSet) Close() {
+	remote.cancel()
+}
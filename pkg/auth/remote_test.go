@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type jwksServer struct {
+	issuer *Authenticator
+
+	mu       sync.Mutex
+	requests int
+	failing  bool
+}
+
+func (server *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.requests++
+	if server.failing {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "max-age=3600")
+	_ = json.NewEncoder(w).Encode(server.issuer.VerificationKeys())
+}
+
+func (server *jwksServer) fail() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.failing = true
+}
+
+func (server *jwksServer) numRequests() int {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.requests
+}
+
+func TestRemoteJwkSet(t *testing.T) {
+	t.Parallel()
+	issuer := &Authenticator{ExpiresAfter: 100 * time.Second}
+	if err := issuer.SetupKeys(&KeyConfig{Generate: true, Algorithm: "ES256"}); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	jwks := &jwksServer{issuer: issuer}
+	server := httptest.NewServer(jwks)
+	defer server.Close()
+
+	verifier := &Authenticator{}
+	if err := verifier.SetupKeys(&KeyConfig{JwksURL: server.URL}); err != nil {
+		t.Fatalf("failed to setup verification keys: %v", err)
+	}
+	defer verifier.Close()
+
+	sign := func() string {
+		token, err := issuer.SignJwtClaims(&testClaims{UserID: "123"})
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return token
+	}
+	assertValid := func(token string) {
+		t.Helper()
+		if valid, _, err := verifier.Validate(token, &testClaims{}); err != nil || !valid {
+			t.Errorf("expected token to be valid: %v", err)
+		}
+	}
+
+	oldToken := sign()
+	assertValid(oldToken)
+	if n := jwks.numRequests(); n != 1 {
+		t.Errorf("expected cached JWK set after 1 request but got %d requests", n)
+	}
+
+	// unknown key ids trigger a refresh
+	key, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := issuer.RotateKey(key); err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+	assertValid(sign())
+	if n := jwks.numRequests(); n != 2 {
+		t.Errorf("expected refresh for unknown key id but got %d requests", n)
+	}
+
+	// refreshes caused by unknown key ids are rate limited
+	key, err = GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := issuer.RotateKey(key); err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+	if valid, _, err := verifier.Validate(sign(), &testClaims{}); err == nil || valid {
+		t.Error("expected token with unknown key id to be invalid while refreshes are rate limited")
+	}
+	if n := jwks.numRequests(); n != 2 {
+		t.Errorf("expected rate limited refresh but got %d requests", n)
+	}
+
+	// the last good JWK set is used when fetching fails
+	jwks.fail()
+	if _, err := verifier.RemoteJwks.refresh.Refresh(context.Background(), server.URL); err == nil {
+		t.Error("expected refresh to fail")
+	}
+	assertValid(oldToken)
+}
+
+func TestRemoteJwkSetLimitsFailedFetches(t *testing.T) {
+	t.Parallel()
+	issuer := &Authenticator{ExpiresAfter: 100 * time.Second}
+	if err := issuer.SetupKeys(&KeyConfig{Generate: true, Algorithm: "ES256"}); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	token, err := issuer.SignJwtClaims(&testClaims{UserID: "123"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	jwks := &jwksServer{issuer: issuer}
+	server := httptest.NewServer(jwks)
+	defer server.Close()
+
+	// the context of the request is used to fetch the JWK set
+	verifier := &Authenticator{RemoteJwks: NewRemoteJwkSet(context.Background(), server.URL, nil)}
+	defer verifier.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := verifier.ValidateContext(ctx, token, &testClaims{}); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected error %v but got %v", ErrUnknownKeyID, err)
+	}
+	if n := jwks.numRequests(); n != 0 {
+		t.Errorf("expected no request with a cancelled context but got %d requests", n)
+	}
+
+	// fetching an unavailable JWK set is rate limited
+	jwks.fail()
+	failing := &Authenticator{RemoteJwks: NewRemoteJwkSet(context.Background(), server.URL, nil)}
+	defer failing.Close()
+	for i := 0; i < 3; i++ {
+		if _, _, err := failing.Validate(token, &testClaims{}); !errors.Is(err, ErrUnknownKeyID) {
+			t.Errorf("expected error %v but got %v", ErrUnknownKeyID, err)
+		}
+	}
+	if n := jwks.numRequests(); n != 1 {
+		t.Errorf("expected 1 request to fetch the unavailable JWK set but got %d requests", n)
+	}
+}
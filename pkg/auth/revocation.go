@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// RevocationStore keeps track of revoked tokens
+//
+// Revocations only need to be kept until expiresAt, after which
+// the affected tokens are rejected because they are expired.
+// A zero expiresAt means the revocation never expires.
+// Expiry is compared against now, the time of the clock of the Authenticator.
+type RevocationStore interface {
+	// Revoke revokes the token with the given `jti`
+	Revoke(ctx context.Context, id string, expiresAt, now time.Time) error
+	// RevokeSubject revokes all tokens of a subject issued before the given time
+	RevokeSubject(ctx context.Context, subject string, before, expiresAt, now time.Time) error
+	// IsRevoked reports whether a token with the given claims has been revoked
+	IsRevoked(ctx context.Context, claims *jwt.RegisteredClaims, now time.Time) (bool, error)
+}
+
+type subjectRevocation struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+// MemoryRevocationStore is an in-memory RevocationStore
+//
+// Revocations are evicted once they expire.
+type MemoryRevocationStore struct {
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	subjects map[string]subjectRevocation
+}
+
+// NewMemoryRevocationStore creates a new in-memory revocation store
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens:   make(map[string]time.Time),
+		subjects: make(map[string]subjectRevocation),
+	}
+}
+
+func expired(expiresAt time.Time, now time.Time) bool {
+	return !expiresAt.IsZero() && now.After(expiresAt)
+}
+
+// laterExpiry returns the later of two expiry times, where zero means never
+func laterExpiry(a, b time.Time) time.Time {
+	if a.IsZero() || b.IsZero() {
+		return time.Time{}
+	}
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Revoke revokes a single token
+func (store *MemoryRevocationStore) Revoke(ctx context.Context, id string, expiresAt, now time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.prune(now)
+	if previous, ok := store.tokens[id]; ok {
+		expiresAt = laterExpiry(previous, expiresAt)
+	}
+	store.tokens[id] = expiresAt
+	return nil
+}
+
+// RevokeSubject revokes all tokens of a subject issued before the given time
+func (store *MemoryRevocationStore) RevokeSubject(ctx context.Context, subject string, before, expiresAt, now time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.prune(now)
+	if previous, ok := store.subjects[subject]; ok {
+		if previous.before.After(before) {
+			before = previous.before
+		}
+		expiresAt = laterExpiry(previous.expiresAt, expiresAt)
+	}
+	store.subjects[subject] = subjectRevocation{before: before, expiresAt: expiresAt}
+	return nil
+}
+
+// IsRevoked reports whether a token has been revoked
+//
+// Tokens of a revoked subject without an `iat` claim are considered revoked.
+func (store *MemoryRevocationStore) IsRevoked(ctx context.Context, claims *jwt.RegisteredClaims, now time.Time) (bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if expiresAt, ok := store.tokens[claims.ID]; ok && claims.ID != "" && !expired(expiresAt, now) {
+		return true, nil
+	}
+	revocation, ok := store.subjects[claims.Subject]
+	if !ok || claims.Subject == "" || expired(revocation.expiresAt, now) {
+		return false, nil
+	}
+	return claims.IssuedAt == nil || claims.IssuedAt.Time.Before(revocation.before), nil
+}
+
+// Prune evicts revocations that expired before now
+func (store *MemoryRevocationStore) Prune(now time.Time) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.prune(now)
+}
+
+func (store *MemoryRevocationStore) prune(now time.Time) {
+	for id, expiresAt := range store.tokens {
+		if expired(expiresAt, now) {
+			delete(store.tokens, id)
+		}
+	}
+	for subject, revocation := range store.subjects {
+		if expired(revocation.expiresAt, now) {
+			delete(store.subjects, subject)
+		}
+	}
+}
+
+func (auth *Authenticator) revocationStore() (RevocationStore, error) {
+	if auth.Revocations == nil {
+		return nil, errors.New("no revocation store configured")
+	}
+	return auth.Revocations, nil
+}
+
+// RevokeToken revokes a single token by its `jti` claim, e.g. on logout
+//
+// The revocation is kept until the token expires.
+func (auth *Authenticator) RevokeToken(ctx context.Context, claims Claims) error {
+	store, err := auth.revocationStore()
+	if err != nil {
+		return err
+	}
+	reg := claims.GetRegisteredClaims()
+	if reg.ID == "" {
+		return errors.New("token has no id")
+	}
+	var expiresAt time.Time
+	if reg.ExpiresAt != nil {
+		expiresAt = reg.ExpiresAt.Time.Add(auth.Validation.Leeway)
+	}
+	return store.Revoke(ctx, reg.ID, expiresAt, auth.now())
+}
+
+// RevokeSubject revokes all tokens of a subject that were issued before the given time
+//
+// The revocation is kept for ExpiresAfter, tokens signed using a longer TTL
+// must be revoked in the store directly. If refresh tokens are configured,
+// the revocation is kept until the refresh tokens issued before have expired.
+func (auth *Authenticator) RevokeSubject(ctx context.Context, subject string, before time.Time) error {
+	store, err := auth.revocationStore()
+	if err != nil {
+		return err
+	}
+	keep := auth.ExpiresAfter
+	if auth.RefreshTokens != nil && auth.refreshExpiresAfter() > keep {
+		keep = auth.refreshExpiresAfter()
+	}
+	expiresAt := before.Add(keep + auth.Validation.Leeway)
+	return store.RevokeSubject(ctx, subject, before, expiresAt, auth.now())
+}
+
+// checkRevoked returns ErrTokenRevoked if the token has been revoked
+func (auth *Authenticator) checkRevoked(ctx context.Context, claims Claims, now time.Time) error {
+	if auth.Revocations == nil {
+		return nil
+	}
+	revoked, err := auth.Revocations.IsRevoked(ctx, claims.GetRegisteredClaims(), now)
+	if err != nil {
+		return fmt.Errorf("failed to check revocation: %v", err)
+	}
+	if revoked {
+		return &ValidationError{Reason: ErrTokenRevoked}
+	}
+	return nil
+}
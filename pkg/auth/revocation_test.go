@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestSignAssignsUniqueID(t *testing.T) {
+	test := new(test).setup(t)
+
+	first, second := &testClaims{}, &testClaims{}
+	if _, err := test.authenticator.SignJwtClaims(first); err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, err := test.authenticator.SignJwtClaims(second); err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if first.ID == "" || first.ID == second.ID {
+		t.Errorf("expected unique token ids but got %q and %q", first.ID, second.ID)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	test := new(test).setup(t)
+	test.authenticator.Revocations = NewMemoryRevocationStore()
+	ctx := context.Background()
+
+	revoked, err := test.authenticator.Sign(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	other, err := test.authenticator.Sign(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	claims, err := ValidateAs[*testClaims](test.authenticator, revoked)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if err := test.authenticator.RevokeToken(ctx, claims); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if _, err := ValidateAs[*testClaims](test.authenticator, revoked); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected error %v but got %v", ErrTokenRevoked, err)
+	}
+	if _, err := ValidateAs[*testClaims](test.authenticator, other); err != nil {
+		t.Errorf("expected other token to be valid: %v", err)
+	}
+}
+
+func TestRevokeSubject(t *testing.T) {
+	test := new(test).setup(t)
+	test.authenticator.Revocations = NewMemoryRevocationStore()
+
+	before, err := test.authenticator.Sign(&testClaims{}, WithSubject("user-1"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	otherSubject, err := test.authenticator.Sign(&testClaims{}, WithSubject("user-2"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if err := test.authenticator.RevokeSubject(context.Background(), "user-1", time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to revoke subject: %v", err)
+	}
+	if _, err := ValidateAs[*testClaims](test.authenticator, before); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected error %v but got %v", ErrTokenRevoked, err)
+	}
+	if _, err := ValidateAs[*testClaims](test.authenticator, otherSubject); err != nil {
+		t.Errorf("expected token of other subject to be valid: %v", err)
+	}
+}
+
+func TestMemoryRevocationStoreEvictsExpired(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := store.Revoke(ctx, "expired", now.Add(-time.Second), now); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if err := store.Revoke(ctx, "active", now.Add(time.Hour), now); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if err := store.RevokeSubject(ctx, "subject", now, now.Add(-time.Second), now); err != nil {
+		t.Fatalf("failed to revoke subject: %v", err)
+	}
+	store.Prune(now)
+	if len(store.tokens) != 1 || len(store.subjects) != 0 {
+		t.Errorf("expected expired revocations to be evicted, got %v and %v", store.tokens, store.subjects)
+	}
+
+	issuedAt := jwt.NewNumericDate(now.Add(-time.Minute))
+	if revoked, _ := store.IsRevoked(ctx, &jwt.RegisteredClaims{ID: "active", IssuedAt: issuedAt}, now); !revoked {
+		t.Error("expected token to be revoked")
+	}
+	if revoked, _ := store.IsRevoked(ctx, &jwt.RegisteredClaims{Subject: "subject", IssuedAt: issuedAt}, now); revoked {
+		t.Error("expected expired subject revocation to be ignored")
+	}
+}
+
+func TestRevocationsExpireByClockOfAuthenticator(t *testing.T) {
+	test := new(test).setup(t)
+	store := NewMemoryRevocationStore()
+	test.authenticator.Revocations = store
+	ctx := context.Background()
+
+	// far from the system clock, which must not expire revocations
+	now := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	test.authenticator.Clock = ClockFunc(func() time.Time { return now })
+
+	token, err := test.authenticator.Sign(&testClaims{}, WithSubject("user-1"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	claims, err := ValidateAs[*testClaims](test.authenticator, token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if err := test.authenticator.RevokeToken(ctx, claims); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if err := test.authenticator.RevokeSubject(ctx, "user-1", now); err != nil {
+		t.Fatalf("failed to revoke subject: %v", err)
+	}
+
+	now = now.Add(test.authenticator.ExpiresAfter - time.Second)
+	if _, err := ValidateAs[*testClaims](test.authenticator, token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("expected error %v until the token expires but got %v", ErrTokenRevoked, err)
+	}
+	now = now.Add(2 * time.Second)
+	store.Prune(now)
+	if len(store.tokens) != 0 || len(store.subjects) != 0 {
+		t.Errorf("expected revocations to expire with the token, got %v and %v", store.tokens, store.subjects)
+	}
+}
+
+// contextRevocationStore fails if the context of a check is done, like a store backed by a database
+type contextRevocationStore struct {
+	*MemoryRevocationStore
+}
+
+func (store contextRevocationStore) IsRevoked(ctx context.Context, claims *jwt.RegisteredClaims, now time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return store.MemoryRevocationStore.IsRevoked(ctx, claims, now)
+}
+
+func TestRevocationCheckUsesContextOfValidation(t *testing.T) {
+	test := new(test).setup(t)
+	test.authenticator.Revocations = contextRevocationStore{NewMemoryRevocationStore()}
+
+	token, err := test.authenticator.Sign(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, _, err := test.authenticator.ValidateContext(context.Background(), token, &testClaims{}); err != nil {
+		t.Errorf("expected token to be valid: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if valid, _, err := test.authenticator.ValidateContext(ctx, token, &testClaims{}); valid || err == nil {
+		t.Error("expected revocation check with a cancelled context to fail")
+	}
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrSecretTooShort is returned when a shared secret is shorter than the output of its hash function
+var ErrSecretTooShort = errors.New("shared secret is too short")
+
+// SharedSecret is a symmetric key for signing and verifying tokens using HMAC
+//
+// It is meant for small internal deployments where every service that verifies tokens may also sign them.
+// Unlike public keys, the secret must never be published, e.g. in a JWK set.
+type SharedSecret struct {
+	// ID is the key id (`kid`) of signed tokens.
+	// Tokens must carry the same key id, or none if the id is empty.
+	ID string
+	// Algorithm is HS256, HS384 or HS512, defaults to HS256
+	Algorithm string
+	// Key must be at least as long as the output of the hash function, i.e. 32, 48 or 64 bytes
+	Key []byte
+}
+
+// NewSharedSecret creates a shared secret for the given HMAC algorithm (HS256, HS384 or HS512)
+//
+// The key must be at least as long as the output of the hash function (RFC 7518, section 3.2).
+func NewSharedSecret(alg string, key []byte) (*SharedSecret, error) {
+	secret := &SharedSecret{Algorithm: alg, Key: key}
+	if _, err := secret.method(); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// GenerateSharedSecret generates a random shared secret for the given HMAC algorithm (HS256, HS384 or HS512)
+func GenerateSharedSecret(alg string) (*SharedSecret, error) {
+	method, err := hmacSigningMethod(alg)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, method.Hash.Size())
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return NewSharedSecret(alg, key)
+}
+
+// hmacSigningMethod returns the HMAC signing method for an algorithm, defaulting to HS256
+func hmacSigningMethod(alg string) (*jwt.SigningMethodHMAC, error) {
+	switch alg {
+	case "", jwt.SigningMethodHS256.Alg():
+		return jwt.SigningMethodHS256, nil
+	case jwt.SigningMethodHS384.Alg():
+		return jwt.SigningMethodHS384, nil
+	case jwt.SigningMethodHS512.Alg():
+		return jwt.SigningMethodHS512, nil
+	}
+	return nil, fmt.Errorf("unsupported HMAC algorithm %q", alg)
+}
+
+// method returns the signing method after checking the length of the key
+func (secret *SharedSecret) method() (*jwt.SigningMethodHMAC, error) {
+	method, err := hmacSigningMethod(secret.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if minLength := method.Hash.Size(); len(secret.Key) < minLength {
+		return nil, fmt.Errorf("%w: %v requires at least %d bytes, but got %d", ErrSecretTooShort, method.Alg(), minLength, len(secret.Key))
+	}
+	return method, nil
+}
+
+// sign signs claims using HMAC
+func (secret *SharedSecret) sign(claims Claims) (string, error) {
+	method, err := secret.method()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if secret.ID != "" {
+		token.Header["kid"] = secret.ID
+	}
+	return token.SignedString(secret.Key)
+}
+
+// verificationKey returns the secret after checking that the token is signed using the same HMAC algorithm
+//
+// Tokens signed with any other algorithm are rejected,
+// so that a token can never be verified with a public key and vice versa.
+func (secret *SharedSecret) verificationKey(t *jwt.Token) (interface{}, error) {
+	method, err := secret.method()
+	if err != nil {
+		return nil, err
+	}
+	if alg := t.Method.Alg(); alg != method.Alg() {
+		return nil, fmt.Errorf("%w: expected %v signing method, but got %v", ErrInvalidSigningMethod, method.Alg(), alg)
+	}
+	if kid, ok := t.Header["kid"]; (ok || secret.ID != "") && kid != secret.ID {
+		return nil, fmt.Errorf("%w: expected key id %q, but got %v", ErrUnknownKeyID, secret.ID, kid)
+	}
+	return secret.Key, nil
+}
+
+// loadSecretFile reads a secret from a file without a trailing newline
+func loadSecretFile(path string) ([]byte, error) {
+	secret, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	// only a line ending is removed, so that binary secrets ending in \r are kept
+	if bytes.HasSuffix(secret, []byte("\r\n")) {
+		return secret[:len(secret)-2], nil
+	}
+	return bytes.TrimSuffix(secret, []byte("\n")), nil
+}
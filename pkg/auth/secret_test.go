@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+func TestSharedSecretRoundTrip(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	for _, alg := range []string{"HS256", "HS384", "HS512"} {
+		secret, err := GenerateSharedSecret(alg)
+		if err != nil {
+			t.Fatalf("failed to generate %s secret: %v", alg, err)
+		}
+		// secret files hold printable secrets, e.g. generated using `openssl rand -base64 64`
+		secretFile := filepath.Join(dir, alg)
+		if err := os.WriteFile(secretFile, []byte(base64.StdEncoding.EncodeToString(secret.Key)+"\n"), 0o600); err != nil {
+			t.Fatalf("failed to write secret: %v", err)
+		}
+		authenticator := &Authenticator{ExpiresAfter: time.Minute}
+		if err := authenticator.SetupKeys(&KeyConfig{SecretFile: secretFile, Algorithm: alg, Generate: true}); err != nil {
+			t.Fatalf("failed to setup %s secret: %v", alg, err)
+		}
+		if authenticator.SignKey != nil || authenticator.VerificationKeys().Len() != 0 {
+			t.Errorf("expected no asymmetric keys to be generated or published")
+		}
+		token, err := authenticator.SignJwtClaims(&testClaims{UserID: "123"})
+		if err != nil {
+			t.Fatalf("failed to sign %s token: %v", alg, err)
+		}
+		claims, err := ValidateAs[*testClaims](authenticator, token)
+		if err != nil {
+			t.Fatalf("expected %s token to be valid: %v", alg, err)
+		}
+		if claims.UserID != "123" {
+			t.Errorf("expected user id 123 but got %q", claims.UserID)
+		}
+	}
+}
+
+func TestLoadSecretFileRemovesLineEnding(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	cases := []struct {
+		content  string
+		expected string
+	}{
+		{"secret", "secret"},
+		{"secret\n", "secret"},
+		{"secret\r\n", "secret"},
+		{"secret\r", "secret\r"},
+		{"secret\r\r\n", "secret\r"},
+		{"secret\n\n", "secret\n"},
+	}
+	for i, c := range cases {
+		path := filepath.Join(dir, strconv.Itoa(i))
+		if err := os.WriteFile(path, []byte(c.content), 0o600); err != nil {
+			t.Fatalf("failed to write secret: %v", err)
+		}
+		secret, err := loadSecretFile(path)
+		if err != nil {
+			t.Fatalf("failed to load secret: %v", err)
+		}
+		if string(secret) != c.expected {
+			t.Errorf("expected secret %q from %q but got %q", c.expected, c.content, secret)
+		}
+	}
+}
+
+func TestSharedSecretMinimumLength(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		alg    string
+		length int
+		err    error
+	}{
+		{"", 31, ErrSecretTooShort},
+		{"HS256", 32, nil},
+		{"HS384", 47, ErrSecretTooShort},
+		{"HS384", 48, nil},
+		{"HS512", 63, ErrSecretTooShort},
+		{"HS512", 64, nil},
+	}
+	for _, c := range cases {
+		_, err := NewSharedSecret(c.alg, make([]byte, c.length))
+		if !errors.Is(err, c.err) {
+			t.Errorf("expected %q secret of %d bytes to fail with %v but got %v", c.alg, c.length, c.err, err)
+		}
+	}
+	if _, err := NewSharedSecret("RS256", make([]byte, 64)); err == nil {
+		t.Errorf("expected non HMAC algorithm to be rejected")
+	}
+	authenticator := &Authenticator{}
+	if err := authenticator.SetupKeys(&KeyConfig{Secret: "too-short"}); !errors.Is(err, ErrSecretTooShort) {
+		t.Errorf("expected error %v but got %v", ErrSecretTooShort, err)
+	}
+}
+
+func TestSetupKeysRejectsSecretWithAsymmetricKeys(t *testing.T) {
+	t.Parallel()
+	key, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM, err := PrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	config := &KeyConfig{Secret: strings.Repeat("s", 32), Key: string(keyPEM)}
+	if err := (&Authenticator{}).SetupKeys(config); err == nil {
+		t.Errorf("expected secret and signing key not to be configured together")
+	}
+}
+
+// signHMAC signs claims using HMAC with an arbitrary key
+func signHMAC(t *testing.T, method jwt.SigningMethod, kid string, key []byte) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, &testClaims{UserID: "123"})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return tokenString
+}
+
+func TestSharedSecretRejectsOtherKeyTypes(t *testing.T) {
+	test := new(test).setup(t)
+	secret, err := GenerateSharedSecret("HS256")
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	secret.ID = "internal"
+
+	// the asymmetric keys are ignored when a secret is configured
+	hmacValidator := &Authenticator{Secret: secret, SignKey: test.authenticator.SignKey, JwkSet: test.authenticator.JwkSet}
+	rsaToken, err := test.authenticator.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	otherSecret, err := GenerateSharedSecret("HS384")
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	cases := []struct {
+		token  string
+		reason error
+	}{
+		{rsaToken, ErrInvalidSigningMethod},
+		{signHMAC(t, jwt.SigningMethodHS384, secret.ID, otherSecret.Key), ErrInvalidSigningMethod},
+		{signHMAC(t, jwt.SigningMethodHS256, secret.ID, otherSecret.Key), ErrInvalidSignature},
+		{signHMAC(t, jwt.SigningMethodHS256, "other", secret.Key), ErrUnknownKeyID},
+		{signHMAC(t, jwt.SigningMethodHS256, "", secret.Key), ErrUnknownKeyID},
+	}
+	for _, c := range cases {
+		if valid, _, err := hmacValidator.Validate(c.token, &testClaims{}); valid || !errors.Is(err, c.reason) {
+			t.Errorf("expected error %v but got %v", c.reason, err)
+		}
+	}
+	if valid, _, err := hmacValidator.Validate(signHMAC(t, jwt.SigningMethodHS256, secret.ID, secret.Key), &testClaims{}); !valid || err != nil {
+		t.Errorf("expected token to be valid: %v", err)
+	}
+	if err := hmacValidator.RotateKey(test.authenticator.SignKey); err == nil {
+		t.Errorf("expected rotation of asymmetric keys to fail when using a secret")
+	}
+}
+
+func TestAsymmetricKeysRejectHMACTokens(t *testing.T) {
+	test := new(test).setup(t)
+	signingKey, err := test.authenticator.SigningKey()
+	if err != nil {
+		t.Fatalf("failed to get signing key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(signingKey.Key.Public())
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	jwksJSON, err := ToJwksJSON(signingKey.Key.Public())
+	if err != nil {
+		t.Fatalf("failed to encode JWK set: %v", err)
+	}
+
+	// a symmetric key in the JWK set must not be used to verify HMAC tokens either
+	secret := []byte(strings.Repeat("s", 32))
+	symmetricKey, err := jwk.New(secret)
+	if err != nil {
+		t.Fatalf("failed to create symmetric key: %v", err)
+	}
+	if err := symmetricKey.Set(jwk.KeyIDKey, "symmetric"); err != nil {
+		t.Fatalf("failed to set key id: %v", err)
+	}
+	test.authenticator.JwkSet.Add(symmetricKey)
+
+	for _, token := range []string{
+		signHMAC(t, jwt.SigningMethodHS256, signingKey.ID, publicKeyPEM),
+		signHMAC(t, jwt.SigningMethodHS256, signingKey.ID, der),
+		signHMAC(t, jwt.SigningMethodHS512, signingKey.ID, jwksJSON),
+		signHMAC(t, jwt.SigningMethodHS256, "symmetric", secret),
+	} {
+		if valid, _, err := test.authenticator.Validate(token, &testClaims{}); valid || !errors.Is(err, ErrInvalidSigningMethod) {
+			t.Errorf("expected error %v but got %v", ErrInvalidSigningMethod, err)
+		}
+	}
+}
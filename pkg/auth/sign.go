@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SignOption configures the claims of a single token signed with Sign
+type SignOption func(*signOptions)
+
+type signOptions struct {
+	ttl       *time.Duration
+	audiences []string
+	subject   string
+	id        string
+	notBefore time.Time
+}
+
+// WithTTL sets the lifetime of the token, overriding ExpiresAfter of the authenticator
+func WithTTL(ttl time.Duration) SignOption {
+	return func(opts *signOptions) {
+		opts.ttl = &ttl
+	}
+}
+
+// WithAudience adds audiences to the token in addition to the audience of the authenticator
+func WithAudience(audiences ...string) SignOption {
+	return func(opts *signOptions) {
+		opts.audiences = append(opts.audiences, audiences...)
+	}
+}
+
+// WithSubject sets the `sub` claim of the token
+func WithSubject(subject string) SignOption {
+	return func(opts *signOptions) {
+		opts.subject = subject
+	}
+}
+
+// WithID sets the `jti` claim of the token
+func WithID(id string) SignOption {
+	return func(opts *signOptions) {
+		opts.id = id
+	}
+}
+
+// WithNotBefore sets the `nbf` claim of the token
+func WithNotBefore(notBefore time.Time) SignOption {
+	return func(opts *signOptions) {
+		opts.notBefore = notBefore
+	}
+}
+
+// appendUnique appends values that are not yet contained in list
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		if value != "" && !contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// Sign signs JWT claims and returns the token string
+//
+// Unlike SignJwtClaims, registered claims that are already set are kept.
+// The issuer and audience of the authenticator are only added if missing,
+// and the expiration defaults to ExpiresAfter unless set or overridden using WithTTL.
+func (auth *Authenticator) Sign(claims Claims, opts ...SignOption) (string, error) {
+	var options signOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	now := auth.now()
+	reg := claims.GetRegisteredClaims()
+	reg.IssuedAt = jwt.NewNumericDate(now)
+	if options.ttl != nil {
+		reg.ExpiresAt = jwt.NewNumericDate(now.Add(*options.ttl))
+	} else if reg.ExpiresAt == nil {
+		reg.ExpiresAt = jwt.NewNumericDate(now.Add(auth.ExpiresAfter))
+	}
+	if reg.Issuer == "" {
+		reg.Issuer = auth.Issuer
+	}
+	reg.Audience = appendUnique(reg.Audience, auth.Audience)
+	reg.Audience = appendUnique(reg.Audience, options.audiences...)
+	if options.subject != "" {
+		reg.Subject = options.subject
+	}
+	if options.id != "" {
+		reg.ID = options.id
+	}
+	if !options.notBefore.IsZero() {
+		reg.NotBefore = jwt.NewNumericDate(options.notBefore)
+	}
+	return auth.sign(claims)
+}
+
+// newClaims returns a new, empty instance of the claims type C
+func newClaims[C Claims]() (C, error) {
+	var claims C
+	typ := reflect.TypeOf(claims)
+	if typ == nil || typ.Kind() != reflect.Pointer {
+		return claims, fmt.Errorf("claims type %v must be a pointer", typ)
+	}
+	return reflect.New(typ.Elem()).Interface().(C), nil
+}
+
+// ValidateAs validates a token and returns its claims as the concrete type C
+//
+// C must be a pointer type, e.g. `ValidateAs[*MyClaims](authenticator, token)`.
+// Errors are of type *ValidationError, see Validate.
+func ValidateAs[C Claims](auth *Authenticator, tokenString string) (C, error) {
+	claims, err := newClaims[C]()
+	if err != nil {
+		return claims, err
+	}
+	valid, _, err := auth.Validate(tokenString, claims)
+	if err != nil {
+		var empty C
+		return empty, err
+	}
+	if !valid {
+		var empty C
+		return empty, &ValidationError{Reason: ErrTokenInvalid}
+	}
+	return claims, nil
+}
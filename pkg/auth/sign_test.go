@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestSignOptions(t *testing.T) {
+	test := new(test).setup(t)
+
+	notBefore := time.Now().Add(-time.Minute).Truncate(time.Second)
+	tokenString, err := test.authenticator.Sign(
+		&testClaims{UserID: "123"},
+		WithTTL(time.Hour),
+		WithAudience("other-audience"),
+		WithSubject("user-123"),
+		WithID("token-1"),
+		WithNotBefore(notBefore),
+	)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	claims, err := ValidateAs[*testClaims](test.authenticator, tokenString)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if claims.UserID != "123" || claims.Subject != "user-123" || claims.ID != "token-1" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if expected := []string{"mock-audience", "other-audience"}; !reflect.DeepEqual([]string(claims.Audience), expected) {
+		t.Errorf("expected audience %v but got %v", expected, claims.Audience)
+	}
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl != time.Hour {
+		t.Errorf("expected ttl %v but got %v", time.Hour, ttl)
+	}
+	if !claims.NotBefore.Time.Equal(notBefore) {
+		t.Errorf("expected not before %v but got %v", notBefore, claims.NotBefore)
+	}
+}
+
+func TestSignKeepsRegisteredClaims(t *testing.T) {
+	test := new(test).setup(t)
+
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	tokenString, err := test.authenticator.Sign(&testClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "other-issuer",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	// only the issuer of the authenticator is accepted by default
+	test.authenticator.Validation.Issuers = []string{"other-issuer"}
+	claims, err := ValidateAs[*testClaims](test.authenticator, tokenString)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+	if claims.Issuer != "other-issuer" {
+		t.Errorf("expected issuer %q but got %q", "other-issuer", claims.Issuer)
+	}
+	if !claims.ExpiresAt.Time.Equal(expiresAt) {
+		t.Errorf("expected expiration %v but got %v", expiresAt, claims.ExpiresAt)
+	}
+}
+
+func TestValidateAsReturnsValidationErrors(t *testing.T) {
+	test := new(test).setup(t)
+
+	claims, err := ValidateAs[*testClaims](test.authenticator, "invalid-token")
+	if !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("expected error %v but got %v", ErrTokenMalformed, err)
+	}
+	if claims != nil {
+		t.Errorf("expected no claims but got %v", claims)
+	}
+
+	// claims must be a concrete pointer type
+	if _, err := ValidateAs[Claims](test.authenticator, "invalid-token"); err == nil {
+		t.Error("expected error for interface claims type")
+	}
+}
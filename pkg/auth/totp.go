@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned when verifying one-time passwords
+var (
+	// ErrInvalidOTP means the one-time password is wrong or outside of the skew window
+	ErrInvalidOTP = errors.New("one-time password is invalid")
+	// ErrOTPReused means the one-time password, or a later one, has already been used
+	ErrOTPReused = errors.New("one-time password has already been used")
+)
+
+// Defaults of TOTP as used by common authenticator apps
+const (
+	DefaultTOTPDigits = 6
+	DefaultTOTPPeriod = 30 * time.Second
+	DefaultTOTPSkew   = 1
+)
+
+// totpSecretSize is the size of generated secrets, which matches the output of HMAC-SHA1 (RFC 4226, section 4)
+const totpSecretSize = 20
+
+var totpSecretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// UsedOTPStore remembers the time steps of verified one-time passwords to reject replays
+type UsedOTPStore interface {
+	// Use records that a subject used the code of a time step until expiresAt,
+	// where now is the time of the TOTP clock that verified the code.
+	// It must atomically report false if the subject already used the code of the same or a later time step.
+	Use(ctx context.Context, subject string, step uint64, expiresAt, now time.Time) (bool, error)
+}
+
+type usedOTP struct {
+	step      uint64
+	expiresAt time.Time
+}
+
+// MemoryUsedOTPStore is an in-memory UsedOTPStore
+//
+// Used time steps are evicted once the codes can no longer be verified.
+type MemoryUsedOTPStore struct {
+	mu   sync.Mutex
+	used map[string]usedOTP
+}
+
+// NewMemoryUsedOTPStore creates a new in-memory store of used one-time passwords
+func NewMemoryUsedOTPStore() *MemoryUsedOTPStore {
+	return &MemoryUsedOTPStore{used: make(map[string]usedOTP)}
+}
+
+// Use records the time step of a verified code unless the subject used the same or a later one
+func (store *MemoryUsedOTPStore) Use(ctx context.Context, subject string, step uint64, expiresAt, now time.Time) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for key, used := range store.used {
+		if expired(used.expiresAt, now) {
+			delete(store.used, key)
+		}
+	}
+	if used, ok := store.used[subject]; ok && used.step >= step {
+		return false, nil
+	}
+	store.used[subject] = usedOTP{step: step, expiresAt: expiresAt}
+	return true, nil
+}
+
+// TOTP generates and verifies time-based one-time passwords (RFC 6238) using HMAC-SHA1
+//
+// Secrets are base32 encoded, as expected by authenticator apps.
+type TOTP struct {
+	// Issuer is shown by authenticator apps next to the account, e.g. the name of the service
+	Issuer string
+	// Digits of a code, 6 to 8, defaults to DefaultTOTPDigits
+	Digits int
+	// Period is the lifetime of a code, defaults to DefaultTOTPPeriod
+	Period time.Duration
+	// Skew is the number of periods before and after the current one whose codes are accepted
+	// to tolerate clock drift and delayed input
+	Skew int
+	// Used remembers verified codes, so that every code can only be used once
+	Used UsedOTPStore
+	// Clock defaults to the system clock
+	Clock Clock
+}
+
+// NewTOTP creates a TOTP with the defaults of common authenticator apps and an in-memory store of used codes
+func NewTOTP(issuer string) *TOTP {
+	return &TOTP{
+		Issuer: issuer,
+		Digits: DefaultTOTPDigits,
+		Period: DefaultTOTPPeriod,
+		Skew:   DefaultTOTPSkew,
+		Used:   NewMemoryUsedOTPStore(),
+	}
+}
+
+// GenerateTOTPSecret generates a random 160 bit secret encoded as base32 without padding
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpSecretEncoding.EncodeToString(secret), nil
+}
+
+// decodeTOTPSecret decodes a base32 secret, ignoring case, spaces and padding
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	key, err := totpSecretEncoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("TOTP secret must be base32 encoded")
+	}
+	return key, nil
+}
+
+func (totp *TOTP) digits() (int, error) {
+	switch {
+	case totp.Digits == 0:
+		return DefaultTOTPDigits, nil
+	case totp.Digits < 6 || totp.Digits > 8:
+		return 0, fmt.Errorf("TOTP codes must have 6 to 8 digits, but got %d", totp.Digits)
+	}
+	return totp.Digits, nil
+}
+
+func (totp *TOTP) period() time.Duration {
+	if totp.Period < time.Second {
+		return DefaultTOTPPeriod
+	}
+	return totp.Period.Truncate(time.Second)
+}
+
+func (totp *TOTP) skew() int {
+	if totp.Skew < 0 {
+		return 0
+	}
+	return totp.Skew
+}
+
+func (totp *TOTP) now() time.Time {
+	if totp.Clock == nil {
+		return time.Now()
+	}
+	return totp.Clock.Now()
+}
+
+// step returns the time step (RFC 6238, section 4.2) at time t
+func (totp *TOTP) step(t time.Time) uint64 {
+	return uint64(t.Unix()) / uint64(totp.period()/time.Second)
+}
+
+// hotp computes the HOTP value (RFC 4226, section 5.3) for a time step
+func hotp(key []byte, step uint64, digits int) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], step)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulo)
+}
+
+// Code returns the code of a base32 encoded secret at time t
+func (totp *TOTP) Code(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	digits, err := totp.digits()
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, totp.step(t), digits), nil
+}
+
+// ProvisioningURI returns the `otpauth://` URI of a secret for an account, e.g. to display it as a QR code
+//
+// See https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func (totp *TOTP) ProvisioningURI(account, secret string) (string, error) {
+	if _, err := decodeTOTPSecret(secret); err != nil {
+		return "", err
+	}
+	digits, err := totp.digits()
+	if err != nil {
+		return "", err
+	}
+	label := url.PathEscape(account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	if totp.Issuer != "" {
+		label = url.PathEscape(totp.Issuer) + ":" + label
+		query.Set("issuer", totp.Issuer)
+	}
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(digits))
+	query.Set("period", strconv.Itoa(int(totp.period()/time.Second)))
+	return "otpauth://totp/" + label + "?" + query.Encode(), nil
+}
+
+// Verify checks the code of a subject against its base32 encoded secret
+//
+// Codes of up to Skew periods before or after the current one are accepted.
+// Every code can only be used once, and codes older than the last used code of the subject are rejected.
+// Returns ErrInvalidOTP or ErrOTPReused if the code is not accepted.
+func (totp *TOTP) Verify(ctx context.Context, subject, secret, code string) error {
+	if totp.Used == nil {
+		return errors.New("no store for used one-time passwords configured")
+	}
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return err
+	}
+	digits, err := totp.digits()
+	if err != nil {
+		return err
+	}
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != digits {
+		return ErrInvalidOTP
+	}
+
+	skew := totp.skew()
+	now := totp.now()
+	current := totp.step(now)
+	var matched uint64
+	found := false
+	for offset := -skew; offset <= skew; offset++ {
+		step := current + uint64(offset)
+		if offset < 0 && current < uint64(-offset) {
+			continue
+		}
+		// all codes of the window are compared to not leak which one matched
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step, digits)), []byte(code)) == 1 {
+			matched, found = step, true
+		}
+	}
+	if !found {
+		return ErrInvalidOTP
+	}
+
+	// the code can be verified until the window no longer includes its time step
+	expiresAt := time.Unix(int64((matched+uint64(skew)+1)*uint64(totp.period()/time.Second)), 0)
+	fresh, err := totp.Used.Use(ctx, subject, matched, expiresAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to record used one-time password: %v", err)
+	}
+	if !fresh {
+		return ErrOTPReused
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestTOTPCodesMatchRFC6238(t *testing.T) {
+	t.Parallel()
+	// test vectors of RFC 6238, appendix B for SHA1
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	totp := &TOTP{Digits: 8, Period: 30 * time.Second}
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+	for unix, expected := range vectors {
+		code, err := totp.Code(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+		if code != expected {
+			t.Errorf("expected code %s at %d but got %s", expected, unix, code)
+		}
+	}
+}
+
+func TestTOTPVerifySkewAndReplay(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	totp := NewTOTP("mock-issuer")
+	totp.Clock = ClockFunc(func() time.Time { return now })
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	code := func(offset time.Duration) string {
+		code, err := totp.Code(secret, now.Add(offset))
+		if err != nil {
+			t.Fatalf("failed to generate code: %v", err)
+		}
+		return code
+	}
+
+	cases := []struct {
+		subject string
+		code    string
+		err     error
+	}{
+		{"alice", code(-2 * totp.Period), ErrInvalidOTP},
+		{"alice", code(-totp.Period), nil},
+		// codes can only be used once
+		{"alice", code(-totp.Period), ErrOTPReused},
+		{"alice", code(0), nil},
+		{"alice", code(0), ErrOTPReused},
+		// codes older than the last used code are rejected
+		{"bob", code(totp.Period), nil},
+		{"bob", code(0), ErrOTPReused},
+		{"bob", code(2 * totp.Period), ErrInvalidOTP},
+		{"carol", "12345", ErrInvalidOTP},
+	}
+	for _, c := range cases {
+		if err := totp.Verify(ctx, c.subject, secret, c.code); !errors.Is(err, c.err) {
+			t.Errorf("expected code %s of %s to fail with %v but got %v", c.code, c.subject, c.err, err)
+		}
+	}
+
+	// codes of the next period are accepted once the time advanced
+	next := code(totp.Period)
+	now = now.Add(totp.Period)
+	if err := totp.Verify(ctx, "alice", secret, next); err != nil {
+		t.Errorf("expected code of the next period to be valid: %v", err)
+	}
+}
+
+func TestTOTPReplayIsRejectedUntilCodeExpires(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	// far from the system clock, which must not evict used codes
+	now := time.Unix(1700000000, 0)
+	totp := NewTOTP("mock-issuer")
+	totp.Clock = ClockFunc(func() time.Time { return now })
+	store := totp.Used.(*MemoryUsedOTPStore)
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	code, err := totp.Code(secret, now.Add(totp.Period))
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	if err := totp.Verify(ctx, "alice", secret, code); err != nil {
+		t.Fatalf("expected code to be valid: %v", err)
+	}
+
+	// the code stays in the skew window for two more periods
+	for i := 0; i < 2; i++ {
+		now = now.Add(totp.Period)
+		if err := totp.Verify(ctx, "alice", secret, code); !errors.Is(err, ErrOTPReused) {
+			t.Errorf("expected error %v after %d periods but got %v", ErrOTPReused, i+1, err)
+		}
+	}
+
+	// once the clock advanced past the window, the code is invalid and evicted
+	now = now.Add(totp.Period)
+	if err := totp.Verify(ctx, "alice", secret, code); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("expected error %v but got %v", ErrInvalidOTP, err)
+	}
+	current, err := totp.Code(secret, now)
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	if err := totp.Verify(ctx, "bob", secret, current); err != nil {
+		t.Fatalf("expected code to be valid: %v", err)
+	}
+	if _, ok := store.used["alice"]; ok || len(store.used) != 1 {
+		t.Errorf("expected expired code of alice to be evicted but got %v", store.used)
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	t.Parallel()
+	totp := NewTOTP("Example Co")
+	uri, err := totp.ProvisioningURI("alice@example.org", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("failed to create provisioning uri: %v", err)
+	}
+	expected := "otpauth://totp/Example%20Co:alice@example.org?algorithm=SHA1&digits=6&issuer=Example+Co&period=30&secret=JBSWY3DPEHPK3PXP"
+	if uri != expected {
+		t.Errorf("expected uri %q but got %q", expected, uri)
+	}
+	if _, err := totp.ProvisioningURI("alice", "not base32!"); err == nil {
+		t.Errorf("expected invalid secret to be rejected")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	t.Parallel()
+	hasher := &Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLength: 32, SaltLength: 16}
+	codes, hashes, err := GenerateRecoveryCodes(hasher, 3)
+	if err != nil {
+		t.Fatalf("failed to generate recovery codes: %v", err)
+	}
+	if len(codes) != 3 || len(hashes) != 3 {
+		t.Fatalf("expected 3 recovery codes but got %d codes and %d hashes", len(codes), len(hashes))
+	}
+	for i, code := range codes {
+		if len(code) != 19 || strings.Count(code, "-") != 3 || strings.Contains(hashes[i], code) {
+			t.Errorf("expected formatted code that is stored hashed but got %q with hash %q", code, hashes[i])
+		}
+	}
+
+	// codes are accepted regardless of case and separators
+	remaining, err := UseRecoveryCode(hasher, strings.ToUpper(strings.ReplaceAll(codes[1], "-", " ")), hashes)
+	if err != nil {
+		t.Fatalf("expected recovery code to be valid: %v", err)
+	}
+	if len(remaining) != 2 || remaining[0] != hashes[0] || remaining[1] != hashes[2] {
+		t.Errorf("expected the hash of the used code to be removed but got %v", remaining)
+	}
+	if _, err := UseRecoveryCode(hasher, codes[1], remaining); !errors.Is(err, ErrInvalidRecoveryCode) {
+		t.Errorf("expected error %v but got %v", ErrInvalidRecoveryCode, err)
+	}
+	if _, err := UseRecoveryCode(hasher, "aaaa-aaaa-aaaa-aaaa", remaining); !errors.Is(err, ErrInvalidRecoveryCode) {
+		t.Errorf("expected error %v but got %v", ErrInvalidRecoveryCode, err)
+	}
+}
+
+type stepUpClaims struct {
+	AuthMethods
+	jwt.RegisteredClaims
+}
+
+func (claims *stepUpClaims) GetRegisteredClaims() *jwt.RegisteredClaims {
+	return &claims.RegisteredClaims
+}
+
+func TestSignJwtClaimsSetsMultiFactorAuthMethod(t *testing.T) {
+	test := new(test).setup(t)
+	cases := []struct {
+		amr      []string
+		expected []string
+	}{
+		{nil, nil},
+		{[]string{AMRPassword}, []string{AMRPassword}},
+		{[]string{AMRPassword, AMRPassword, AMROTP}, []string{AMRPassword, AMROTP, AMRMultiFactor}},
+	}
+	for _, c := range cases {
+		claims := &stepUpClaims{}
+		claims.AddAuthMethods(c.amr...)
+		token, err := test.authenticator.SignJwtClaims(claims)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		validated, err := ValidateAs[*stepUpClaims](test.authenticator, token)
+		if err != nil {
+			t.Fatalf("expected token to be valid: %v", err)
+		}
+		if strings.Join(validated.AMR, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("expected amr %v but got %v", c.expected, validated.AMR)
+		}
+		if mfa := validated.HasAuthMethod(AMRMultiFactor); mfa != (len(c.expected) > 2) {
+			t.Errorf("expected multi-factor authentication to be %v", !mfa)
+		}
+	}
+}
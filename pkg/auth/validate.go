@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ValidationOptions configures how Validate checks the claims of a token
+type ValidationOptions struct {
+	// Issuers that are accepted, defaults to the Issuer of the authenticator if set
+	Issuers []string
+	// Audiences of which the token must contain at least one, defaults to the Audience of the authenticator if set
+	Audiences []string
+	// AnyIssuer accepts tokens of any issuer if Issuers is empty, instead of only those of the authenticator
+	AnyIssuer bool
+	// AnyAudience accepts tokens for any audience if Audiences is empty, instead of only those for the authenticator
+	AnyAudience bool
+	// Leeway is the tolerated clock skew when checking the exp, nbf and iat claims
+	Leeway time.Duration
+	// RequireExpiresAt requires tokens to have an exp claim
+	RequireExpiresAt bool
+	// RequireNotBefore requires tokens to have a nbf claim
+	RequireNotBefore bool
+	// RequireIssuedAt requires tokens to have an iat claim
+	RequireIssuedAt bool
+	// MaxAge is the maximum age of a token based on its iat claim, tokens of any age are accepted if zero
+	MaxAge time.Duration
+	// RequiredClaims are the names of custom claims that tokens must have
+	RequiredClaims []string
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// withDefaults returns the options with the issuer and audience of the authenticator
+// accepted by default, unless any issuer or audience is accepted explicitly
+func (opts ValidationOptions) withDefaults(issuer, audience string) ValidationOptions {
+	if len(opts.Issuers) < 1 && !opts.AnyIssuer && issuer != "" {
+		opts.Issuers = []string{issuer}
+	}
+	if len(opts.Audiences) < 1 && !opts.AnyAudience && audience != "" {
+		opts.Audiences = []string{audience}
+	}
+	return opts
+}
+
+// validateTimes checks the exp, nbf and iat claims
+func (opts *ValidationOptions) validateTimes(claims *jwt.RegisteredClaims, now time.Time) error {
+	leeway := opts.Leeway
+	if claims.ExpiresAt == nil {
+		if opts.RequireExpiresAt {
+			return fmt.Errorf("%w: exp", ErrMissingClaim)
+		}
+	} else if !now.Add(-leeway).Before(claims.ExpiresAt.Time) {
+		return ErrTokenExpired
+	}
+
+	if claims.NotBefore == nil {
+		if opts.RequireNotBefore {
+			return fmt.Errorf("%w: nbf", ErrMissingClaim)
+		}
+	} else if now.Add(leeway).Before(claims.NotBefore.Time) {
+		return ErrTokenNotValidYet
+	}
+
+	if claims.IssuedAt == nil {
+		if opts.RequireIssuedAt || opts.MaxAge > 0 {
+			return fmt.Errorf("%w: iat", ErrMissingClaim)
+		}
+		return nil
+	}
+	if now.Add(leeway).Before(claims.IssuedAt.Time) {
+		return ErrTokenUsedBeforeIssued
+	}
+	if opts.MaxAge > 0 && now.Sub(claims.IssuedAt.Time) > opts.MaxAge+leeway {
+		return ErrTokenTooOld
+	}
+	return nil
+}
+
+// validateIssuerAndAudience checks the iss and aud claims
+func (opts *ValidationOptions) validateIssuerAndAudience(claims *jwt.RegisteredClaims) error {
+	if len(opts.Issuers) > 0 && !contains(opts.Issuers, claims.Issuer) {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, claims.Issuer)
+	}
+	if len(opts.Audiences) > 0 {
+		for _, aud := range claims.Audience {
+			if contains(opts.Audiences, aud) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %q", ErrInvalidAudience, []string(claims.Audience))
+	}
+	return nil
+}
+
+// validateRequiredClaims checks that the payload of a token contains all required claims
+func (opts *ValidationOptions) validateRequiredClaims(tokenString string) error {
+	if len(opts.RequiredClaims) < 1 {
+		return nil
+	}
+	payload := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenString, payload); err != nil {
+		return err
+	}
+	for _, name := range opts.RequiredClaims {
+		if _, ok := payload[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrMissingClaim, name)
+		}
+	}
+	return nil
+}
+
+// validate checks the claims of a token whose signature has been verified
+func (opts *ValidationOptions) validate(tokenString string, claims Claims, now time.Time) error {
+	reg := claims.GetRegisteredClaims()
+	if err := opts.validateTimes(reg, now); err != nil {
+		return err
+	}
+	if err := opts.validateIssuerAndAudience(reg); err != nil {
+		return err
+	}
+	if err := opts.validateRequiredClaims(tokenString); err != nil {
+		return err
+	}
+	return validateCustomClaims(claims)
+}
+
+// validateCustomClaims calls the Valid method of custom claims
+//
+// The exp, nbf and iat claims are hidden during the call because they have already been checked
+// using the clock and leeway of the validation options, whereas the Valid method of the embedded
+// jwt.RegisteredClaims checks them using the system clock. Claims without their own Valid method
+// are therefore always valid.
+func validateCustomClaims(claims Claims) error {
+	reg := claims.GetRegisteredClaims()
+	expiresAt, notBefore, issuedAt := reg.ExpiresAt, reg.NotBefore, reg.IssuedAt
+	reg.ExpiresAt, reg.NotBefore, reg.IssuedAt = nil, nil, nil
+	defer func() {
+		reg.ExpiresAt, reg.NotBefore, reg.IssuedAt = expiresAt, notBefore, issuedAt
+	}()
+	return claims.Valid()
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestValidationOptions(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	at := func(d time.Duration) *jwt.NumericDate {
+		return jwt.NewNumericDate(now.Add(d))
+	}
+
+	cases := []struct {
+		name     string
+		opts     ValidationOptions
+		claims   jwt.RegisteredClaims
+		expected error
+	}{
+		{"no options", ValidationOptions{}, jwt.RegisteredClaims{}, nil},
+		{"expired", ValidationOptions{}, jwt.RegisteredClaims{ExpiresAt: at(-time.Minute)}, ErrTokenExpired},
+		{"expired within leeway", ValidationOptions{Leeway: 2 * time.Minute}, jwt.RegisteredClaims{ExpiresAt: at(-time.Minute)}, nil},
+		{"missing exp", ValidationOptions{RequireExpiresAt: true}, jwt.RegisteredClaims{}, ErrMissingClaim},
+		{"not yet valid", ValidationOptions{}, jwt.RegisteredClaims{NotBefore: at(time.Minute)}, ErrTokenNotValidYet},
+		{"not yet valid within leeway", ValidationOptions{Leeway: 2 * time.Minute}, jwt.RegisteredClaims{NotBefore: at(time.Minute)}, nil},
+		{"missing nbf", ValidationOptions{RequireNotBefore: true}, jwt.RegisteredClaims{}, ErrMissingClaim},
+		{"issued in the future", ValidationOptions{}, jwt.RegisteredClaims{IssuedAt: at(time.Minute)}, ErrTokenUsedBeforeIssued},
+		{"missing iat", ValidationOptions{RequireIssuedAt: true}, jwt.RegisteredClaims{}, ErrMissingClaim},
+		{"missing iat with max age", ValidationOptions{MaxAge: time.Hour}, jwt.RegisteredClaims{}, ErrMissingClaim},
+		{"too old", ValidationOptions{MaxAge: time.Hour}, jwt.RegisteredClaims{IssuedAt: at(-2 * time.Hour)}, ErrTokenTooOld},
+		{"not too old", ValidationOptions{MaxAge: time.Hour}, jwt.RegisteredClaims{IssuedAt: at(-time.Minute)}, nil},
+		{"valid issuer", ValidationOptions{Issuers: []string{"a", "b"}}, jwt.RegisteredClaims{Issuer: "b"}, nil},
+		{"invalid issuer", ValidationOptions{Issuers: []string{"a", "b"}}, jwt.RegisteredClaims{Issuer: "c"}, ErrInvalidIssuer},
+		{"valid audience", ValidationOptions{Audiences: []string{"a"}}, jwt.RegisteredClaims{Audience: []string{"b", "a"}}, nil},
+		{"invalid audience", ValidationOptions{Audiences: []string{"a"}}, jwt.RegisteredClaims{Audience: []string{"b"}}, ErrInvalidAudience},
+		{"missing audience", ValidationOptions{Audiences: []string{"a"}}, jwt.RegisteredClaims{}, ErrInvalidAudience},
+	}
+	for _, c := range cases {
+		claims := &testClaims{RegisteredClaims: c.claims}
+		err := c.opts.validate("", claims, now)
+		if !errors.Is(err, c.expected) || (c.expected == nil && err != nil) {
+			t.Errorf("%s: expected error %v but got %v", c.name, c.expected, err)
+		}
+	}
+}
+
+func TestValidateChecksAudienceAndRequiredClaims(t *testing.T) {
+	test := new(test).setup(t)
+
+	tokenString, err := test.authenticator.SignJwtClaims(&testClaims{UserID: "123"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	test.authenticator.Validation = ValidationOptions{
+		Issuers:        []string{"mock-issuer"},
+		Audiences:      []string{"mock-audience"},
+		MaxAge:         time.Minute,
+		RequiredClaims: []string{"user_id"},
+	}
+	if valid, _, err := test.authenticator.Validate(tokenString, &testClaims{}); err != nil || !valid {
+		t.Errorf("expected token to be valid: %v", err)
+	}
+
+	test.authenticator.Validation.Audiences = []string{"other-audience"}
+	if _, _, err := test.authenticator.Validate(tokenString, &testClaims{}); !errors.Is(err, ErrInvalidAudience) {
+		t.Errorf("expected error %v but got %v", ErrInvalidAudience, err)
+	}
+
+	test.authenticator.Validation.Audiences = nil
+	test.authenticator.Validation.RequiredClaims = []string{"tenant_id"}
+	if _, _, err := test.authenticator.Validate(tokenString, &testClaims{}); !errors.Is(err, ErrMissingClaim) {
+		t.Errorf("expected error %v but got %v", ErrMissingClaim, err)
+	}
+}
+
+func TestValidateDefaultsToIssuerAndAudienceOfAuthenticator(t *testing.T) {
+	test := new(test).setup(t)
+
+	cases := []struct {
+		claims   jwt.RegisteredClaims
+		opts     ValidationOptions
+		expected error
+	}{
+		{jwt.RegisteredClaims{}, ValidationOptions{}, nil},
+		{jwt.RegisteredClaims{Issuer: "other-issuer"}, ValidationOptions{}, ErrInvalidIssuer},
+		{jwt.RegisteredClaims{Issuer: "other-issuer"}, ValidationOptions{AnyIssuer: true}, nil},
+		{jwt.RegisteredClaims{Issuer: "other-issuer"}, ValidationOptions{Issuers: []string{"other-issuer"}}, nil},
+		{jwt.RegisteredClaims{Audience: []string{"other-audience"}}, ValidationOptions{}, nil},
+	}
+	for _, c := range cases {
+		tokenString, err := test.authenticator.Sign(&testClaims{RegisteredClaims: c.claims})
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		test.authenticator.Validation = c.opts
+		if _, err := ValidateAs[*testClaims](test.authenticator, tokenString); !errors.Is(err, c.expected) || (c.expected == nil && err != nil) {
+			t.Errorf("expected error %v for claims %+v but got %v", c.expected, c.claims, err)
+		}
+	}
+
+	// a token for another audience signed by the same key
+	other := &Authenticator{Issuer: test.authenticator.Issuer, Audience: "other-audience", SignKey: test.authenticator.SignKey, JwkSet: test.authenticator.JwkSet, ExpiresAfter: time.Minute}
+	tokenString, err := other.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	test.authenticator.Validation = ValidationOptions{}
+	if _, err := ValidateAs[*testClaims](test.authenticator, tokenString); !errors.Is(err, ErrInvalidAudience) {
+		t.Errorf("expected error %v but got %v", ErrInvalidAudience, err)
+	}
+	test.authenticator.Validation = ValidationOptions{AnyAudience: true}
+	if _, err := ValidateAs[*testClaims](test.authenticator, tokenString); err != nil {
+		t.Errorf("expected token for any audience to be valid: %v", err)
+	}
+}
+
+type roleClaims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func (claims *roleClaims) GetRegisteredClaims() *jwt.RegisteredClaims {
+	return &claims.RegisteredClaims
+}
+
+func (claims *roleClaims) Valid() error {
+	if err := claims.RegisteredClaims.Valid(); err != nil {
+		return err
+	}
+	if claims.Role == "" {
+		return errors.New("missing role")
+	}
+	return nil
+}
+
+func TestValidateCallsValidOfCustomClaims(t *testing.T) {
+	test := new(test).setup(t)
+	// far from the system clock, which must not be used to check the registered claims
+	now := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	test.authenticator.Clock = ClockFunc(func() time.Time { return now })
+
+	admin, err := test.authenticator.Sign(&roleClaims{Role: "admin"})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	claims := &roleClaims{}
+	if valid, _, err := test.authenticator.Validate(admin, claims); err != nil || !valid {
+		t.Errorf("expected token to be valid: %v", err)
+	}
+	if claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		t.Errorf("expected registered claims to be kept, got %+v", claims.RegisteredClaims)
+	}
+
+	missingRole, err := test.authenticator.Sign(&roleClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if valid, _, err := test.authenticator.Validate(missingRole, &roleClaims{}); valid || !errors.Is(err, ErrTokenInvalid) {
+		t.Errorf("expected error %v but got %v", ErrTokenInvalid, err)
+	}
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultKeyFileWatchInterval is the default interval between checks of the key files for changes
+const DefaultKeyFileWatchInterval = 30 * time.Second
+
+// KeyFileWatcherOptions configures a KeyFileWatcher
+type KeyFileWatcherOptions struct {
+	// Interval between checks of the key files for changes, defaults to DefaultKeyFileWatchInterval
+	Interval time.Duration
+	// GracePeriod during which replaced verification keys remain valid,
+	// defaults to the remaining lifetime of the longest token signed so far plus the validation leeway
+	GracePeriod time.Duration
+	// Metrics configures the namespace and labels of the reload metrics
+	Metrics MetricsOptions
+}
+
+// KeyFileWatcher reloads the signing key and JWK set of an Authenticator when the key files change
+//
+// The files are polled, which also detects updates of Kubernetes secrets that replace a symlink.
+// New keys are swapped in atomically and only if the JWK set contains the public key of the signing key,
+// so that files that are only partially updated are retried on the next check.
+// Verification keys that are no longer in the JWK set remain valid for the grace period.
+//
+// The watcher implements prometheus.Collector and can be registered to export reload metrics.
+type KeyFileWatcher struct {
+	auth        *Authenticator
+	config      KeyConfig
+	gracePeriod time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	errs   chan error
+
+	mu       sync.Mutex
+	checksum [sha256.Size]byte
+
+	reloads     *prometheus.CounterVec
+	lastSuccess prometheus.Gauge
+}
+
+// WatchKeyFiles reloads the keys from the KeyFile, JwksFile, KeyPassphraseFile and CertificateFile of config
+// whenever they change, until the context is cancelled or Close is called.
+//
+// The config must be the one the keys were set up with.
+// Keys rotated with RotateKey take precedence over the reloaded signing key.
+func (auth *Authenticator) WatchKeyFiles(ctx context.Context, config *KeyConfig, options *KeyFileWatcherOptions) (*KeyFileWatcher, error) {
+	if config.hasSecret() {
+		return nil, errors.New("reloading shared secrets is not supported")
+	}
+	if options == nil {
+		options = &KeyFileWatcherOptions{}
+	}
+	interval := options.Interval
+	if interval <= 0 {
+		interval = DefaultKeyFileWatchInterval
+	}
+	watcher := &KeyFileWatcher{
+		auth:        auth,
+		config:      *config,
+		gracePeriod: options.GracePeriod,
+		done:        make(chan struct{}),
+		errs:        make(chan error, 1),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   options.Metrics.Namespace,
+			Name:        "key_file_reloads_total",
+			Help:        "Number of reloads of changed key files by result.",
+			ConstLabels: options.Metrics.ConstLabels,
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   options.Metrics.Namespace,
+			Name:        "key_file_last_reload_success_timestamp_seconds",
+			Help:        "Time of the last successful reload of the key files.",
+			ConstLabels: options.Metrics.ConstLabels,
+		}),
+	}
+	checksum, err := watcher.files()
+	if err != nil {
+		return nil, err
+	}
+	watcher.checksum = checksum
+
+	ctx, watcher.cancel = context.WithCancel(ctx)
+	go watcher.watch(ctx, interval)
+	return watcher, nil
+}
+
+func (watcher *KeyFileWatcher) watch(ctx context.Context, interval time.Duration) {
+	defer close(watcher.done)
+	defer close(watcher.errs)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := watcher.Reload(); err != nil {
+				select {
+				case watcher.errs <- err:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// Errors receives reload errors if it has capacity and is closed when the watcher stops
+func (watcher *KeyFileWatcher) Errors() <-chan error {
+	return watcher.errs
+}
+
+// Close stops watching the key files
+func (watcher *KeyFileWatcher) Close() {
+	watcher.cancel()
+	<-watcher.done
+}
+
+// Reload swaps in the keys if any of the key files changed and reports whether the keys were swapped.
+//
+// On error, the current keys remain in use.
+func (watcher *KeyFileWatcher) Reload() (bool, error) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	checksum, err := watcher.files()
+	if err != nil {
+		return false, watcher.failed(err)
+	}
+	if checksum == watcher.checksum {
+		return false, nil
+	}
+	signKey, jwkSet, err := watcher.load()
+	if err != nil {
+		return false, watcher.failed(err)
+	}
+	watcher.auth.swapKeys(signKey, jwkSet, watcher.gracePeriod)
+	watcher.checksum = checksum
+	watcher.reloads.WithLabelValues("success").Inc()
+	watcher.lastSuccess.SetToCurrentTime()
+	return true, nil
+}
+
+func (watcher *KeyFileWatcher) failed(err error) error {
+	watcher.reloads.WithLabelValues("failure").Inc()
+	return fmt.Errorf("failed to reload keys: %w", err)
+}
+
+// files returns the checksum of the contents of all watched files
+func (watcher *KeyFileWatcher) files() ([sha256.Size]byte, error) {
+	var checksum [sha256.Size]byte
+	hash := sha256.New()
+	config := &watcher.config
+	for _, path := range []string{config.KeyFile, config.JwksFile, config.KeyPassphraseFile, config.CertificateFile} {
+		if path == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return checksum, err
+		}
+		contents := sha256.Sum256(data)
+		hash.Write(contents[:])
+	}
+	copy(checksum[:], hash.Sum(nil))
+	return checksum, nil
+}
+
+// load loads the keys and checks that the JWK set verifies tokens of the signing key
+func (watcher *KeyFileWatcher) load() (crypto.Signer, jwk.Set, error) {
+	passphrase, err := watcher.config.passphrase()
+	if err != nil {
+		return nil, nil, err
+	}
+	signKey, jwkSet, err := loadKeys(&watcher.config, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	if jwkSet == nil {
+		return nil, nil, errors.New("missing signing key or jwk set")
+	}
+	if signKey != nil {
+		if _, ok := findPublicKey(jwkSet, signKey.Public()); !ok {
+			return nil, nil, errors.New("JWK set does not contain the public key of the signing key")
+		}
+	}
+	return signKey, jwkSet, nil
+}
+
+// Describe implements prometheus.Collector
+func (watcher *KeyFileWatcher) Describe(ch chan<- *prometheus.Desc) {
+	watcher.reloads.Describe(ch)
+	watcher.lastSuccess.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (watcher *KeyFileWatcher) Collect(ch chan<- prometheus.Metric) {
+	watcher.reloads.Collect(ch)
+	watcher.lastSuccess.Collect(ch)
+}
+
+// swapKeys replaces the signing key and JWK set.
+//
+// A nil signing key keeps the current signing key.
+// Verification keys that are not in the new JWK set remain valid for the grace period
+// or, if it is not positive, until all tokens they signed have expired.
+func (auth *Authenticator) swapKeys(signKey crypto.Signer, jwkSet jwk.Set, gracePeriod time.Duration) {
+	now := auth.now()
+	auth.mu.Lock()
+	defer auth.mu.Unlock()
+	retireAt := now.Add(gracePeriod)
+	if gracePeriod <= 0 {
+		retireAt = auth.retireAt(now)
+	}
+	retired := auth.retired[:0]
+	for _, entry := range auth.retired {
+		if _, replaced := jwkSet.LookupKeyID(entry.key.KeyID()); !replaced && !entry.expired(now) {
+			retired = append(retired, entry)
+		}
+	}
+	for i := 0; auth.JwkSet != nil && i < auth.JwkSet.Len(); i++ {
+		key, _ := auth.JwkSet.Get(i)
+		if _, exists := jwkSet.LookupKeyID(key.KeyID()); exists {
+			continue
+		}
+		if publicKey, err := jwk.PublicKeyOf(key); err == nil {
+			retired = append(retired, &keyringEntry{key: publicKey, expiresAt: retireAt})
+		}
+	}
+	if signKey != nil {
+		auth.SignKey = signKey
+	}
+	auth.JwkSet, auth.retired = jwkSet, retired
+}
+
+// lookupRetiredKeyID finds a replaced verification key with the given id within its grace period
+func (auth *Authenticator) lookupRetiredKeyID(kid string) (jwk.Key, bool) {
+	now := auth.now()
+	auth.mu.RLock()
+	defer auth.mu.RUnlock()
+	for _, entry := range auth.retired {
+		if entry.key.KeyID() == kid && !entry.expired(now) {
+			return entry.key, true
+		}
+	}
+	return nil, false
+}
+
+// retiredKeys returns the replaced verification keys within their grace period
+func (auth *Authenticator) retiredKeys() jwk.Set {
+	now := auth.now()
+	auth.mu.RLock()
+	defer auth.mu.RUnlock()
+	set := jwk.NewSet()
+	for _, entry := range auth.retired {
+		if !entry.expired(now) {
+			set.Add(entry.key)
+		}
+	}
+	return set
+}
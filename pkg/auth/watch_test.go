@@ -0,0 +1,218 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// writeKeyFiles writes a new signing key and its JWK set to the files of config
+func writeKeyFiles(t *testing.T, config *KeyConfig, writeJwks bool) {
+	t.Helper()
+	key, err := GenerateSigningKey("ES256")
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	keyPEM, err := PrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	if err := writeFileAtomic(config.KeyFile, keyPEM); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	if !writeJwks {
+		return
+	}
+	jwksJSON, err := ToJwksJSON(key.Public())
+	if err != nil {
+		t.Fatalf("failed to encode JWK set: %v", err)
+	}
+	if err := writeFileAtomic(config.JwksFile, jwksJSON); err != nil {
+		t.Fatalf("failed to write JWK set: %v", err)
+	}
+}
+
+func setupKeyFiles(t *testing.T) (*Authenticator, *KeyConfig) {
+	t.Helper()
+	dir := t.TempDir()
+	config := &KeyConfig{
+		KeyFile:  filepath.Join(dir, "key.pem"),
+		JwksFile: filepath.Join(dir, "jwks.json"),
+	}
+	writeKeyFiles(t, config, true)
+	authenticator := &Authenticator{ExpiresAfter: time.Minute}
+	if err := authenticator.SetupKeys(config); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	return authenticator, config
+}
+
+func TestKeyFileWatcherReloadsKeys(t *testing.T) {
+	t.Parallel()
+	authenticator, config := setupKeyFiles(t)
+	oldToken, err := authenticator.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	watcher, err := authenticator.WatchKeyFiles(context.Background(), config, &KeyFileWatcherOptions{
+		Interval:    time.Hour,
+		GracePeriod: 500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to watch key files: %v", err)
+	}
+	defer watcher.Close()
+
+	if reloaded, err := watcher.Reload(); reloaded || err != nil {
+		t.Errorf("expected unchanged files not to be reloaded: %v", err)
+	}
+
+	// a new key without the matching JWK set is not swapped in
+	writeKeyFiles(t, config, false)
+	if reloaded, err := watcher.Reload(); reloaded || err == nil {
+		t.Errorf("expected reload of mismatching key files to fail")
+	}
+	if failures := testutil.ToFloat64(watcher.reloads.WithLabelValues("failure")); failures != 1 {
+		t.Errorf("expected 1 failed reload but got %v", failures)
+	}
+
+	writeKeyFiles(t, config, true)
+	if reloaded, err := watcher.Reload(); !reloaded || err != nil {
+		t.Fatalf("expected keys to be reloaded: %v", err)
+	}
+	if successes := testutil.ToFloat64(watcher.reloads.WithLabelValues("success")); successes != 1 {
+		t.Errorf("expected 1 successful reload but got %v", successes)
+	}
+	newToken, err := authenticator.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	for _, token := range []string{oldToken, newToken} {
+		if valid, _, err := authenticator.Validate(token, &testClaims{}); err != nil || !valid {
+			t.Errorf("expected token to be valid after reload: %v", err)
+		}
+	}
+	if keys := authenticator.VerificationKeys(); keys.Len() != 2 {
+		t.Errorf("expected old and new verification keys but got %d", keys.Len())
+	}
+
+	// the old key is removed after the grace period
+	time.Sleep(600 * time.Millisecond)
+	if _, _, err := authenticator.Validate(oldToken, &testClaims{}); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected error %v but got %v", ErrUnknownKeyID, err)
+	}
+}
+
+func TestKeyFileWatcherKeepsKeysUntilLongestTokenExpires(t *testing.T) {
+	t.Parallel()
+	authenticator, config := setupKeyFiles(t)
+	now := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	authenticator.Clock = ClockFunc(func() time.Time { return now })
+	authenticator.Validation.Leeway = 10 * time.Second
+
+	longToken, err := authenticator.Sign(&testClaims{}, WithTTL(2*authenticator.ExpiresAfter))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	watcher, err := authenticator.WatchKeyFiles(context.Background(), config, &KeyFileWatcherOptions{Interval: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to watch key files: %v", err)
+	}
+	defer watcher.Close()
+	writeKeyFiles(t, config, true)
+	if reloaded, err := watcher.Reload(); !reloaded || err != nil {
+		t.Fatalf("expected keys to be reloaded: %v", err)
+	}
+
+	// past the lifetime of tokens signed with ExpiresAfter
+	now = now.Add(authenticator.ExpiresAfter + authenticator.Validation.Leeway + time.Second)
+	if _, err := ValidateAs[*testClaims](authenticator, longToken); err != nil {
+		t.Errorf("expected token with longer lifetime to be valid after reload: %v", err)
+	}
+	now = now.Add(authenticator.ExpiresAfter)
+	if _, err := ValidateAs[*testClaims](authenticator, longToken); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected error %v but got %v", ErrUnknownKeyID, err)
+	}
+}
+
+func TestKeyFileWatchersRegisterWithDistinctLabels(t *testing.T) {
+	t.Parallel()
+	registry := prometheus.NewRegistry()
+	for _, name := range []string{"users", "services"} {
+		authenticator, config := setupKeyFiles(t)
+		watcher, err := authenticator.WatchKeyFiles(context.Background(), config, &KeyFileWatcherOptions{
+			Interval: time.Hour,
+			Metrics:  MetricsOptions{ConstLabels: prometheus.Labels{"authenticator": name}},
+		})
+		if err != nil {
+			t.Fatalf("failed to watch key files: %v", err)
+		}
+		defer watcher.Close()
+		if err := registry.Register(watcher); err != nil {
+			t.Errorf("failed to register watcher %q: %v", name, err)
+		}
+	}
+}
+
+func TestKeyFileWatcherPollsConcurrently(t *testing.T) {
+	t.Parallel()
+	authenticator, config := setupKeyFiles(t)
+	watcher, err := authenticator.WatchKeyFiles(context.Background(), config, &KeyFileWatcherOptions{
+		Interval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to watch key files: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				token, err := authenticator.SignJwtClaims(&testClaims{})
+				if err != nil {
+					t.Errorf("failed to sign token: %v", err)
+					return
+				}
+				if valid, _, err := authenticator.Validate(token, &testClaims{}); err != nil || !valid {
+					t.Errorf("expected token to be valid during reload: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 3; i++ {
+		writeKeyFiles(t, config, true)
+		deadline := time.Now().Add(5 * time.Second)
+		for testutil.ToFloat64(watcher.reloads.WithLabelValues("success")) < float64(i+1) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for reload %d", i+1)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	cancel()
+	wg.Wait()
+	watcher.Close()
+}
+
+func TestWatchKeyFilesRequiresFiles(t *testing.T) {
+	t.Parallel()
+	authenticator, config := setupKeyFiles(t)
+	if err := os.Remove(config.JwksFile); err != nil {
+		t.Fatalf("failed to remove JWK set: %v", err)
+	}
+	if _, err := authenticator.WatchKeyFiles(context.Background(), config, nil); err == nil {
+		t.Errorf("expected missing key files to fail")
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/romnn/go-service/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// APIKeyMetadataKey is the metadata key that holds the API key
+const APIKeyMetadataKey = "x-api-key"
+
+// APIKeyFromMetadata extracts the API key from the incoming metadata
+func APIKeyFromMetadata(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", auth.ErrMissingToken
+	}
+	values := md.Get(APIKeyMetadataKey)
+	if len(values) < 1 || values[0] == "" {
+		return "", auth.ErrMissingToken
+	}
+	return values[0], nil
+}
+
+func authenticateAPIKey(ctx context.Context, keys *auth.APIKeys) (context.Context, error) {
+	apiKey, err := APIKeyFromMetadata(ctx)
+	if err != nil {
+		return nil, StatusError(err)
+	}
+	principal, err := keys.Authenticate(ctx, apiKey)
+	if err != nil {
+		return nil, StatusError(err)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// APIKeyUnaryServerInterceptor returns an interceptor that authenticates the API key and injects its principal into the request context
+func APIKeyUnaryServerInterceptor(keys *auth.APIKeys) grpc.UnaryServerInterceptor {
+	return unaryServerInterceptor(func(ctx context.Context) (context.Context, error) {
+		return authenticateAPIKey(ctx, keys)
+	})
+}
+
+// APIKeyStreamServerInterceptor returns an interceptor that authenticates the API key and injects its principal into the stream context
+func APIKeyStreamServerInterceptor(keys *auth.APIKeys) grpc.StreamServerInterceptor {
+	return streamServerInterceptor(func(ctx context.Context) (context.Context, error) {
+		return authenticateAPIKey(ctx, keys)
+	})
+}
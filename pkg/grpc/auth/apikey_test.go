@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/romnn/go-service/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAPIKeyInterceptorInjectsPrincipal(t *testing.T) {
+	t.Parallel()
+	keys := &auth.APIKeys{Store: auth.NewMemoryAPIKeyStore()}
+	apiKey, _, err := keys.Create(context.Background(), "service-a", []string{"read"}, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	interceptor := APIKeyUnaryServerInterceptor(keys)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(APIKeyMetadataKey, apiKey))
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		principal, ok := auth.PrincipalFromContext(ctx)
+		if !ok || principal.Subject != "service-a" {
+			t.Errorf("unexpected principal %+v", principal)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		metadata.NewIncomingContext(context.Background(), metadata.Pairs(APIKeyMetadataKey, "invalid_key")),
+	} {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			t.Error("handler must not be called")
+			return nil, nil
+		})
+		assertCode(t, err, codes.Unauthenticated)
+	}
+}
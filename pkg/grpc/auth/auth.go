@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/romnn/go-service/pkg/auth"
+	grpcutils "github.com/romnn/go-service/pkg/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// AuthorizationMetadataKey is the metadata key that holds the bearer token
+	AuthorizationMetadataKey = "authorization"
+	bearerScheme             = "bearer"
+)
+
+type claimsKey struct{}
+
+// ClaimsFactory returns a new, empty claims instance that a token is decoded into
+type ClaimsFactory func() auth.Claims
+
+// WithClaims injects validated claims into context
+func WithClaims(ctx context.Context, claims auth.Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext extracts validated claims from context
+func ClaimsFromContext(ctx context.Context) (auth.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(auth.Claims)
+	return claims, ok
+}
+
+// TokenFromMetadata extracts the bearer token from the incoming metadata
+func TokenFromMetadata(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("%w: missing metadata", auth.ErrMissingToken)
+	}
+	values := md.Get(AuthorizationMetadataKey)
+	if len(values) < 1 {
+		return "", auth.ErrMissingToken
+	}
+	parts := strings.SplitN(values[0], " ", 2)
+	if len(parts) < 2 || !strings.EqualFold(parts[0], bearerScheme) {
+		return "", fmt.Errorf("%w: expected authorization scheme %q", auth.ErrMissingToken, bearerScheme)
+	}
+	token := strings.TrimSpace(parts[1])
+	if token == "" {
+		return "", auth.ErrMissingToken
+	}
+	return token, nil
+}
+
+func authenticate(ctx context.Context, authenticator *auth.Authenticator, newClaims ClaimsFactory) (context.Context, error) {
+	token, err := TokenFromMetadata(ctx)
+	if err != nil {
+		return nil, StatusError(err)
+	}
+	claims := newClaims()
+	valid, _, err := authenticator.ValidateContext(ctx, token, claims)
+	if err != nil {
+		return nil, StatusError(err)
+	}
+	if !valid {
+		return nil, StatusError(&auth.ValidationError{Reason: auth.ErrTokenInvalid})
+	}
+	principal := &auth.Principal{
+		Subject: claims.GetRegisteredClaims().Subject,
+		Method:  auth.AuthMethodJWT,
+		Claims:  claims,
+	}
+	if scopeClaims, ok := claims.(ScopeClaims); ok {
+		principal.Scopes = scopeClaims.GetScopes()
+	}
+	return auth.WithPrincipal(WithClaims(ctx, claims), principal), nil
+}
+
+// authenticateFunc authenticates a request and returns the context for the handler
+type authenticateFunc func(ctx context.Context) (context.Context, error)
+
+func unaryServerInterceptor(authenticate authenticateFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		newCtx, err := authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(newCtx, req)
+	}
+}
+
+func streamServerInterceptor(authenticate authenticateFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, err := authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		wrapped := grpcutils.WrapServerStream(stream)
+		wrapped.WrappedContext = newCtx
+		return handler(srv, wrapped)
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor that validates the bearer token and injects its claims into the request context
+func UnaryServerInterceptor(authenticator *auth.Authenticator, newClaims ClaimsFactory) grpc.UnaryServerInterceptor {
+	return unaryServerInterceptor(func(ctx context.Context) (context.Context, error) {
+		return authenticate(ctx, authenticator, newClaims)
+	})
+}
+
+// StreamServerInterceptor returns an interceptor that validates the bearer token and injects its claims into the stream context
+func StreamServerInterceptor(authenticator *auth.Authenticator, newClaims ClaimsFactory) grpc.StreamServerInterceptor {
+	return streamServerInterceptor(func(ctx context.Context) (context.Context, error) {
+		return authenticate(ctx, authenticator, newClaims)
+	})
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/romnn/go-service/pkg/auth"
+	"github.com/romnn/go-service/pkg/auth/authtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type testClaims struct {
+	UserID string `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+func (claims *testClaims) GetRegisteredClaims() *jwt.RegisteredClaims {
+	return &claims.RegisteredClaims
+}
+
+func newTestClaims() auth.Claims {
+	return &testClaims{}
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *testServerStream) Context() context.Context {
+	return stream.ctx
+}
+
+type test struct {
+	authenticator *auth.Authenticator
+}
+
+func (test *test) setup(t *testing.T) *test {
+	t.Parallel()
+
+	test.authenticator = authtest.NewAuthenticator(auth.SystemClock)
+	test.authenticator.ExpiresAfter = 100 * time.Second
+	return test
+}
+
+func (test *test) token(t *testing.T, userID string) string {
+	token, err := test.authenticator.SignJwtClaims(&testClaims{UserID: userID})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func withAuthorization(value string) context.Context {
+	return metadata.NewIncomingContext(
+		context.Background(),
+		metadata.Pairs(AuthorizationMetadataKey, value),
+	)
+}
+
+func assertCode(t *testing.T, err error, expected codes.Code) {
+	t.Helper()
+	if code := status.Code(err); code != expected {
+		t.Errorf("expected status code %v but got %v (%v)", expected, code, err)
+	}
+}
+
+func TestUnaryInterceptorInjectsClaims(t *testing.T) {
+	test := new(test).setup(t)
+	interceptor := UnaryServerInterceptor(test.authenticator, newTestClaims)
+
+	ctx := withAuthorization("Bearer " + test.token(t, "123"))
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		claims, ok := ClaimsFromContext(ctx)
+		if !ok {
+			t.Fatal("expected claims in context")
+		}
+		if userID := claims.(*testClaims).UserID; userID != "123" {
+			t.Errorf("expected user ID %q but got %q", "123", userID)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestUnaryInterceptorRejectsBadTokens(t *testing.T) {
+	test := new(test).setup(t)
+	interceptor := UnaryServerInterceptor(test.authenticator, newTestClaims)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Error("handler must not be called")
+		return nil, nil
+	}
+
+	contexts := []context.Context{
+		context.Background(),
+		withAuthorization(""),
+		withAuthorization("Bearer"),
+		withAuthorization("Basic dXNlcjpwYXNz"),
+		withAuthorization("Bearer invalid-token"),
+	}
+	for _, ctx := range contexts {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		assertCode(t, err, codes.Unauthenticated)
+	}
+}
+
+func TestStreamInterceptorInjectsClaims(t *testing.T) {
+	test := new(test).setup(t)
+	interceptor := StreamServerInterceptor(test.authenticator, newTestClaims)
+
+	stream := &testServerStream{ctx: withAuthorization("bearer " + test.token(t, "123"))}
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		if _, ok := ClaimsFromContext(stream.Context()); !ok {
+			t.Error("expected claims in stream context")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stream = &testServerStream{ctx: withAuthorization("Bearer invalid-token")}
+	err = interceptor(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		t.Error("handler must not be called")
+		return nil
+	})
+	assertCode(t, err, codes.Unauthenticated)
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+
+	"github.com/romnn/go-service/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// PeerCertificate returns the verified client certificate of the peer
+//
+// The server must be configured to verify client certificates,
+// e.g. using tls.RequireAndVerifyClientCert.
+func PeerCertificate(ctx context.Context) (*x509.Certificate, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, auth.ErrMissingCertificate
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, auth.ErrMissingCertificate
+	}
+	return auth.VerifiedPeerCertificate(tlsInfo.State.VerifiedChains)
+}
+
+func authenticateCertificate(ctx context.Context, mapper *auth.CertificateMapper) (context.Context, error) {
+	cert, err := PeerCertificate(ctx)
+	if err != nil {
+		return nil, StatusError(err)
+	}
+	principal, err := mapper.Map(cert)
+	if err != nil {
+		return nil, StatusError(err)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// CertificateUnaryServerInterceptor returns an interceptor that maps the verified client certificate
+// to a principal and injects it into the request context
+func CertificateUnaryServerInterceptor(mapper *auth.CertificateMapper) grpc.UnaryServerInterceptor {
+	return unaryServerInterceptor(func(ctx context.Context) (context.Context, error) {
+		return authenticateCertificate(ctx, mapper)
+	})
+}
+
+// CertificateStreamServerInterceptor returns an interceptor that maps the verified client certificate
+// to a principal and injects it into the stream context
+func CertificateStreamServerInterceptor(mapper *auth.CertificateMapper) grpc.StreamServerInterceptor {
+	return streamServerInterceptor(func(ctx context.Context) (context.Context, error) {
+		return authenticateCertificate(ctx, mapper)
+	})
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/romnn/go-service/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestCertificateInterceptorInjectsPrincipal(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t)
+	spiffeID, _ := url.Parse("spiffe://example.org/ns/prod/sa/client")
+	serverCert := ca.issue(t, &x509.Certificate{DNSNames: []string{"server.example.org"}})
+	clientCert := ca.issue(t, &x509.Certificate{URIs: []*url.URL{spiffeID}})
+	unknownCert := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "unknown"}})
+
+	mapper := &auth.CertificateMapper{Rules: []auth.CertificateRule{
+		{URI: "spiffe://example.org/ns/prod/*"},
+	}}
+	var subject string
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientCAs:    ca.pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		})),
+		grpc.ChainUnaryInterceptor(
+			CertificateUnaryServerInterceptor(mapper),
+			func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if principal, ok := auth.PrincipalFromContext(ctx); ok {
+					subject = principal.Subject
+				}
+				return handler(ctx, req)
+			},
+		),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	check := func(cert tls.Certificate) error {
+		conn, err := grpc.Dial("bufnet",
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				return listener.Dial()
+			}),
+			grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+				Certificates: []tls.Certificate{cert},
+				RootCAs:      ca.pool,
+				ServerName:   "server.example.org",
+			})),
+		)
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		defer conn.Close()
+		_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		return err
+	}
+
+	if err := check(clientCert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if subject != spiffeID.String() {
+		t.Errorf("expected subject %q but got %q", spiffeID, subject)
+	}
+	assertCode(t, check(unknownCert), codes.Unauthenticated)
+}
+
+func TestCertificateInterceptorRequiresTLS(t *testing.T) {
+	t.Parallel()
+	interceptor := CertificateUnaryServerInterceptor(&auth.CertificateMapper{})
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		t.Error("handler must not be called")
+		return nil, nil
+	})
+	assertCode(t, err, codes.Unauthenticated)
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/romnn/go-service/pkg/auth"
+	grpcutils "github.com/romnn/go-service/pkg/grpc"
+	"github.com/romnn/go-service/pkg/grpc/auth/gen"
+	"github.com/romnn/go-service/pkg/grpc/reflect"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// RoleClaims is implemented by claims that carry roles
+type RoleClaims interface {
+	GetRoles() []string
+}
+
+// ScopeClaims is implemented by claims that carry scopes
+type ScopeClaims interface {
+	GetScopes() []string
+}
+
+// GetPolicy gets the `go_service.auth` policy of a method
+func GetPolicy(info reflect.MethodInfo) (*gen.AuthPolicy, bool) {
+	options := info.Method().Options()
+	if options == nil || !proto.HasExtension(options, gen.E_Auth) {
+		return nil, false
+	}
+	policy, ok := proto.GetExtension(options, gen.E_Auth).(*gen.AuthPolicy)
+	return policy, ok && policy != nil
+}
+
+// PolicyEnforcer enforces the authentication policies declared using the `go_service.auth` method option
+type PolicyEnforcer struct {
+	Registry      reflect.Registry
+	Authenticator *auth.Authenticator
+	NewClaims     ClaimsFactory
+	// DefaultPolicy applies to methods without a policy. If nil, authentication is required.
+	DefaultPolicy *gen.AuthPolicy
+}
+
+func (enforcer *PolicyEnforcer) policy(method string) *gen.AuthPolicy {
+	if info, ok := enforcer.Registry.GetMethodInfo(method); ok {
+		if policy, ok := GetPolicy(info); ok {
+			return policy
+		}
+	}
+	if enforcer.DefaultPolicy != nil {
+		return enforcer.DefaultPolicy
+	}
+	return &gen.AuthPolicy{Required: true}
+}
+
+// allowsAnonymous reports whether callers without a token may call a method
+//
+// Roles and scopes require an authenticated caller, even if the policy allows anonymous callers.
+func allowsAnonymous(policy *gen.AuthPolicy) bool {
+	if len(policy.GetRoles()) > 0 || len(policy.GetScopes()) > 0 {
+		return false
+	}
+	return policy.GetAllowAnonymous() || !policy.GetRequired()
+}
+
+func hasAny(values []string, wanted []string) bool {
+	for _, w := range wanted {
+		for _, v := range values {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasAll(values []string, wanted []string) bool {
+	for _, w := range wanted {
+		if !hasAny(values, []string{w}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Authorize checks that claims satisfy the roles and scopes of a policy
+//
+// Returns an error wrapping auth.ErrInsufficientScope otherwise.
+func Authorize(policy *gen.AuthPolicy, claims auth.Claims) error {
+	if roles := policy.GetRoles(); len(roles) > 0 {
+		var granted []string
+		if roleClaims, ok := claims.(RoleClaims); ok {
+			granted = roleClaims.GetRoles()
+		}
+		if !hasAny(granted, roles) {
+			return fmt.Errorf("%w: missing required role", auth.ErrInsufficientScope)
+		}
+	}
+	if scopes := policy.GetScopes(); len(scopes) > 0 {
+		var granted []string
+		if scopeClaims, ok := claims.(ScopeClaims); ok {
+			granted = scopeClaims.GetScopes()
+		}
+		if !hasAll(granted, scopes) {
+			return fmt.Errorf("%w: missing required scope", auth.ErrInsufficientScope)
+		}
+	}
+	return nil
+}
+
+func (enforcer *PolicyEnforcer) enforce(ctx context.Context, method string) (context.Context, error) {
+	policy := enforcer.policy(method)
+	if _, err := TokenFromMetadata(ctx); err != nil && allowsAnonymous(policy) {
+		return ctx, nil
+	}
+	newCtx, err := authenticate(ctx, enforcer.Authenticator, enforcer.NewClaims)
+	if err != nil {
+		return nil, err
+	}
+	claims, _ := ClaimsFromContext(newCtx)
+	if err := Authorize(policy, claims); err != nil {
+		return nil, StatusError(err)
+	}
+	return newCtx, nil
+}
+
+// UnaryServerInterceptor returns an interceptor that enforces the policy of the called method
+func (enforcer *PolicyEnforcer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		newCtx, err := enforcer.enforce(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(newCtx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that enforces the policy of the called method
+func (enforcer *PolicyEnforcer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, err := enforcer.enforce(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := grpcutils.WrapServerStream(stream)
+		wrapped.WrappedContext = newCtx
+		return handler(srv, wrapped)
+	}
+}
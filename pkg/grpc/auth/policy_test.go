@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/romnn/go-service/pkg/grpc/auth/gen"
+	"github.com/romnn/go-service/pkg/grpc/reflect"
+	"google.golang.org/grpc/codes"
+)
+
+func TestPolicyWithRolesRequiresAuthentication(t *testing.T) {
+	test := new(test).setup(t)
+
+	cases := []struct {
+		policy    *gen.AuthPolicy
+		anonymous codes.Code
+	}{
+		{&gen.AuthPolicy{AllowAnonymous: true}, codes.OK},
+		{&gen.AuthPolicy{}, codes.OK},
+		{&gen.AuthPolicy{Required: true}, codes.Unauthenticated},
+		{&gen.AuthPolicy{AllowAnonymous: true, Roles: []string{"admin"}}, codes.Unauthenticated},
+		{&gen.AuthPolicy{AllowAnonymous: true, Scopes: []string{"read"}}, codes.Unauthenticated},
+	}
+	for _, c := range cases {
+		enforcer := &PolicyEnforcer{
+			Registry:      reflect.NewRegistry(),
+			Authenticator: test.authenticator,
+			NewClaims:     newTestClaims,
+			DefaultPolicy: c.policy,
+		}
+		_, err := enforcer.enforce(withAuthorization(""), "/test.Service/Method")
+		assertCode(t, err, c.anonymous)
+
+		// authenticated callers must still have the roles
+		if len(c.policy.GetRoles()) > 0 {
+			_, err := enforcer.enforce(withAuthorization("Bearer "+test.token(t, "123")), "/test.Service/Method")
+			assertCode(t, err, codes.PermissionDenied)
+		}
+	}
+}
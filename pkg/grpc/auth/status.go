@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/romnn/go-service/pkg/auth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code maps an authentication error to a gRPC status code
+//
+// Missing or invalid credentials map to codes.Unauthenticated and
+// insufficient roles or scopes map to codes.PermissionDenied.
+// An overloaded password hashing pool maps to codes.ResourceExhausted
+// and password policy violations map to codes.InvalidArgument.
+func Code(err error) codes.Code {
+	var validationErr *auth.ValidationError
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, auth.ErrPasswordPolicy):
+		return codes.InvalidArgument
+	case errors.Is(err, auth.ErrPasswordHashingOverloaded):
+		return codes.ResourceExhausted
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, auth.ErrInsufficientScope):
+		return codes.PermissionDenied
+	case errors.Is(err, auth.ErrMissingToken), errors.As(err, &validationErr):
+		return codes.Unauthenticated
+	case errors.Is(err, auth.ErrInvalidOTP), errors.Is(err, auth.ErrOTPReused), errors.Is(err, auth.ErrInvalidRecoveryCode):
+		return codes.Unauthenticated
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code()
+	}
+	return codes.Internal
+}
+
+// PasswordPolicyStatusError converts password policy violations of a request field
+// to a codes.InvalidArgument status error with errdetails.BadRequest field violations
+func PasswordPolicyStatusError(field string, err *auth.PasswordPolicyError) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, violation := range err.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: violation.Description,
+		})
+	}
+	s, detailsErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(badRequest)
+	if detailsErr != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return s.Err()
+}
+
+// StatusError converts an authentication error to a gRPC status error
+//
+// Password policy violations are reported for the `password` field,
+// use PasswordPolicyStatusError for other fields.
+func StatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var policyErr *auth.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return PasswordPolicyStatusError("password", policyErr)
+	}
+	code := Code(err)
+	if code == codes.Internal {
+		return status.Error(code, "failed to authenticate")
+	}
+	return status.Error(code, err.Error())
+}
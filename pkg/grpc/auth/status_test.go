@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/romnn/go-service/pkg/auth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		err      error
+		expected codes.Code
+	}{
+		{auth.ErrMissingToken, codes.Unauthenticated},
+		{&auth.ValidationError{Reason: auth.ErrTokenExpired}, codes.Unauthenticated},
+		{auth.ErrOTPReused, codes.Unauthenticated},
+		{auth.ErrInvalidRecoveryCode, codes.Unauthenticated},
+		{fmt.Errorf("%w: missing required role", auth.ErrInsufficientScope), codes.PermissionDenied},
+		{fmt.Errorf("failed to verify password: %w", auth.ErrPasswordHashingOverloaded), codes.ResourceExhausted},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("database is down"), codes.Internal},
+	}
+	for _, c := range cases {
+		assertCode(t, StatusError(c.err), c.expected)
+	}
+	if err := StatusError(nil); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+}
+
+func TestPasswordPolicyStatusError(t *testing.T) {
+	t.Parallel()
+	policy := auth.PasswordPolicy{MinLength: 8, RequireDigit: true}
+	err := StatusError(policy.Validate("short"))
+	assertCode(t, err, codes.InvalidArgument)
+
+	var fields []string
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+	if expected := []string{"password", "password"}; !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected field violations for %v but got %v", expected, fields)
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+// APIKeyHeader is the header that holds the API key
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyScheme is the scheme of the `WWW-Authenticate` challenge for API keys
+const apiKeyScheme = "APIKey"
+
+// writeAPIKeyError writes an error response with an API key challenge,
+// since the Bearer challenge of WriteError does not apply to API keys
+func writeAPIKeyError(w http.ResponseWriter, realm string, err error) {
+	code := StatusCode(err)
+	if code == http.StatusUnauthorized {
+		challenge := apiKeyScheme
+		if realm != "" {
+			challenge += " realm=" + quote(realm)
+		}
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	http.Error(w, http.StatusText(code), code)
+}
+
+// APIKeyMiddleware returns a middleware that authenticates the API key of a request
+// and injects its principal into the request context
+//
+// Requests without a valid API key are rejected with status 401 and an `APIKey` challenge for the realm.
+func APIKeyMiddleware(keys *auth.APIKeys, realm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := r.Header.Get(APIKeyHeader)
+			if apiKey == "" {
+				writeAPIKeyError(w, realm, auth.ErrMissingToken)
+				return
+			}
+			principal, err := keys.Authenticate(r.Context(), apiKey)
+			if err != nil {
+				writeAPIKeyError(w, realm, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	t.Parallel()
+	keys := &auth.APIKeys{Store: auth.NewMemoryAPIKeyStore()}
+	apiKey, _, err := keys.Create(context.Background(), "service-a", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	handler := APIKeyMiddleware(keys, "api")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok {
+			t.Error("expected principal in request context")
+			return
+		}
+		w.Write([]byte(principal.Subject))
+	}))
+
+	cases := []struct {
+		apiKey string
+		code   int
+	}{
+		{apiKey, http.StatusOK},
+		{"", http.StatusUnauthorized},
+		{"invalid_key", http.StatusUnauthorized},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if c.apiKey != "" {
+			req.Header.Set(APIKeyHeader, c.apiKey)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != c.code {
+			t.Errorf("%q: expected status %d but got %d", c.apiKey, c.code, rec.Code)
+		}
+		if challenge := rec.Header().Get("WWW-Authenticate"); c.code == http.StatusUnauthorized && challenge != `APIKey realm="api"` {
+			t.Errorf("%q: expected API key challenge but got %q", c.apiKey, challenge)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"crypto/x509"
+	"net/http"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+// writeCertificateError writes an error without a bearer challenge, as the client cannot present a token instead
+func writeCertificateError(w http.ResponseWriter, err error) {
+	code := StatusCode(err)
+	http.Error(w, http.StatusText(code), code)
+}
+
+// CertificateMiddleware returns a middleware that maps the verified client certificate
+// of a request to a principal and injects it into the request context
+//
+// The server must be configured to verify client certificates,
+// e.g. using tls.RequireAndVerifyClientCert.
+func CertificateMiddleware(mapper *auth.CertificateMapper) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var verifiedChains [][]*x509.Certificate
+			if r.TLS != nil {
+				verifiedChains = r.TLS.VerifiedChains
+			}
+			cert, err := auth.VerifiedPeerCertificate(verifiedChains)
+			if err != nil {
+				writeCertificateError(w, err)
+				return
+			}
+			principal, err := mapper.Map(cert)
+			if err != nil {
+				writeCertificateError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+func newTestClientCertificate(t *testing.T, commonName string) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	// self-signed client certificate that is trusted directly
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestCertificateMiddleware(t *testing.T) {
+	t.Parallel()
+	clientCert, pool := newTestClientCertificate(t, "billing")
+	mapper := &auth.CertificateMapper{Rules: []auth.CertificateRule{{CommonName: "billing"}}}
+
+	server := httptest.NewUnstartedServer(CertificateMiddleware(mapper)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFromContext(r.Context())
+		if !ok || principal.Subject != "billing" {
+			t.Errorf("unexpected principal %+v", principal)
+		}
+	})))
+	server.TLS = &tls.Config{ClientCAs: pool, ClientAuth: tls.VerifyClientCertIfGiven}
+	server.StartTLS()
+	defer server.Close()
+
+	cases := []struct {
+		certificates []tls.Certificate
+		code         int
+	}{
+		{nil, http.StatusUnauthorized},
+		{[]tls.Certificate{clientCert}, http.StatusOK},
+	}
+	for _, c := range cases {
+		// certificates are sent during the handshake, so every case uses its own connections
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.Certificates = c.certificates
+		client := &http.Client{Transport: transport}
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		res.Body.Close()
+		transport.CloseIdleConnections()
+		if res.StatusCode != c.code {
+			t.Errorf("expected status %d with %d certificates but got %d", c.code, len(c.certificates), res.StatusCode)
+		}
+	}
+}
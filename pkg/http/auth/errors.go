@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+// StatusCode maps an authentication error to an HTTP status code
+//
+// Missing or invalid credentials map to 401 and
+// insufficient roles or scopes map to 403.
+// An overloaded password hashing pool maps to 503 and password policy violations map to 400.
+func StatusCode(err error) int {
+	var validationErr *auth.ValidationError
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, auth.ErrPasswordPolicy):
+		return http.StatusBadRequest
+	case errors.Is(err, auth.ErrPasswordHashingOverloaded):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, auth.ErrInsufficientScope):
+		return http.StatusForbidden
+	case errors.Is(err, auth.ErrMissingToken), errors.As(err, &validationErr):
+		return http.StatusUnauthorized
+	case errors.Is(err, auth.ErrInvalidOTP), errors.Is(err, auth.ErrOTPReused), errors.Is(err, auth.ErrInvalidRecoveryCode):
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// bearerErrorCode returns the RFC 6750 error code of an authentication error
+func bearerErrorCode(err error) string {
+	var validationErr *auth.ValidationError
+	switch {
+	case errors.Is(err, auth.ErrInsufficientScope):
+		return "insufficient_scope"
+	case errors.As(err, &validationErr):
+		return "invalid_token"
+	}
+	// requests without credentials should not include an error code
+	return ""
+}
+
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// Challenge returns the `WWW-Authenticate` header value for an authentication error
+// ref: https://www.rfc-editor.org/rfc/rfc6750#section-3
+func Challenge(realm string, err error) string {
+	var params []string
+	if realm != "" {
+		params = append(params, "realm="+quote(realm))
+	}
+	if code := bearerErrorCode(err); code != "" {
+		params = append(params, "error="+quote(code))
+		params = append(params, "error_description="+quote(err.Error()))
+	}
+	if len(params) < 1 {
+		return "Bearer"
+	}
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+// WriteError writes an authentication error response
+//
+// Responses with status 401 or 403 include a `WWW-Authenticate` challenge.
+// Overload responses with status 503 ask the client to retry.
+// Internal errors are not exposed to the client.
+func WriteError(w http.ResponseWriter, realm string, err error) {
+	code := StatusCode(err)
+	switch code {
+	case http.StatusInternalServerError:
+		http.Error(w, "failed to authenticate", code)
+		return
+	case http.StatusServiceUnavailable:
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, err.Error(), code)
+		return
+	case http.StatusBadRequest:
+		http.Error(w, err.Error(), code)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", Challenge(realm, err))
+	http.Error(w, fmt.Sprintf("%s: %v", http.StatusText(code), err), code)
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+func TestWriteError(t *testing.T) {
+	t.Parallel()
+	expired := &auth.ValidationError{Reason: auth.ErrTokenExpired}
+	cases := []struct {
+		err       error
+		code      int
+		challenge string
+	}{
+		{auth.ErrMissingToken, http.StatusUnauthorized, `Bearer realm="api"`},
+		{expired, http.StatusUnauthorized, `Bearer realm="api", error="invalid_token", error_description="token is expired"`},
+		{auth.ErrInvalidOTP, http.StatusUnauthorized, `Bearer realm="api"`},
+		{auth.ErrOTPReused, http.StatusUnauthorized, `Bearer realm="api"`},
+		{fmt.Errorf("failed to login: %w", auth.ErrInvalidRecoveryCode), http.StatusUnauthorized, `Bearer realm="api"`},
+		{fmt.Errorf("%w: missing required role", auth.ErrInsufficientScope), http.StatusForbidden, `Bearer realm="api", error="insufficient_scope", error_description="insufficient scope: missing required role"`},
+		{auth.ErrPasswordHashingOverloaded, http.StatusServiceUnavailable, ""},
+		{&auth.PasswordPolicyError{}, http.StatusBadRequest, ""},
+		{errors.New("database is down"), http.StatusInternalServerError, ""},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		WriteError(rec, "api", c.err)
+		if rec.Code != c.code {
+			t.Errorf("%v: expected status %d but got %d", c.err, c.code, rec.Code)
+		}
+		if challenge := rec.Header().Get("WWW-Authenticate"); challenge != c.challenge {
+			t.Errorf("%v: expected challenge %q but got %q", c.err, c.challenge, challenge)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package jwks
+
+import (
+	"github.com/romnn/go-service/pkg/auth"
+	"github.com/romnn/go-service/pkg/http/jwks"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Use registers the JWK set and OpenID configuration handlers at their well-known paths
+func Use(e *echo.Echo, authenticator *auth.Authenticator) {
+	e.GET(jwks.JwksPath, echo.WrapHandler(&jwks.Handler{Authenticator: authenticator}))
+	e.GET(jwks.OpenIDConfigurationPath, echo.WrapHandler(&jwks.DiscoveryHandler{Authenticator: authenticator}))
+}
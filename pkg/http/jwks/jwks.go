@@ -0,0 +1,132 @@
+package jwks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+const (
+	// JwksPath is the well-known path of the JWK set
+	JwksPath = "/.well-known/jwks.json"
+	// OpenIDConfigurationPath is the well-known path of the OpenID provider configuration
+	OpenIDConfigurationPath = "/.well-known/openid-configuration"
+	// DefaultMaxAge is the default duration that clients may cache responses for
+	DefaultMaxAge = 15 * time.Minute
+)
+
+// OpenIDConfiguration is a minimal OpenID provider configuration
+// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type OpenIDConfiguration struct {
+	Issuer                           string   `json:"issuer"`
+	JwksURI                          string   `json:"jwks_uri"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// Handler serves the public verification keys of an authenticator as a JWK set
+type Handler struct {
+	Authenticator *auth.Authenticator
+	// MaxAge is the duration that clients may cache the JWK set for, defaults to DefaultMaxAge
+	MaxAge time.Duration
+}
+
+// ServeHTTP implements a `http.Handler`
+// implements https://pkg.go.dev/net/http#Handler
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := json.Marshal(handler.Authenticator.VerificationKeys())
+	if err != nil {
+		http.Error(w, "failed to encode JWK set", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, r, body, handler.MaxAge, true)
+}
+
+// DiscoveryHandler serves the OpenID provider configuration of an authenticator
+type DiscoveryHandler struct {
+	Authenticator *auth.Authenticator
+	// JwksURI is the absolute URL of the JWK set.
+	// If empty, it is derived from the Host and X-Forwarded-Proto headers of the request and JwksPath,
+	// and the configuration must not be cached by shared caches, which could serve it for another host.
+	JwksURI string
+	// MaxAge is the duration that clients may cache the configuration for, defaults to DefaultMaxAge
+	MaxAge time.Duration
+}
+
+// Configuration returns the OpenID provider configuration for a request
+func (handler *DiscoveryHandler) Configuration(r *http.Request) *OpenIDConfiguration {
+	jwksURI := handler.JwksURI
+	if jwksURI == "" {
+		jwksURI = fmt.Sprintf("%s://%s%s", scheme(r), r.Host, JwksPath)
+	}
+	return &OpenIDConfiguration{
+		Issuer:                           handler.Authenticator.Issuer,
+		JwksURI:                          jwksURI,
+		ResponseTypesSupported:           []string{"id_token"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: handler.Authenticator.SigningAlgorithms(),
+	}
+}
+
+// ServeHTTP implements a `http.Handler`
+// implements https://pkg.go.dev/net/http#Handler
+func (handler *DiscoveryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := json.Marshal(handler.Configuration(r))
+	if err != nil {
+		http.Error(w, "failed to encode OpenID configuration", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, r, body, handler.MaxAge, handler.JwksURI != "")
+}
+
+// NewServeMux returns a new http.ServeMux that serves the JWK set and OpenID configuration at their well-known paths
+//
+// The JWKS URI of the OpenID configuration is derived from the request, use a DiscoveryHandler to configure it.
+func NewServeMux(authenticator *auth.Authenticator) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle(JwksPath, &Handler{Authenticator: authenticator})
+	mux.Handle(OpenIDConfigurationPath, &DiscoveryHandler{Authenticator: authenticator})
+	return mux
+}
+
+func scheme(r *http.Request) string {
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		return strings.ToLower(strings.TrimSpace(strings.Split(proto, ",")[0]))
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// writeJSON writes a JSON response with caching headers.
+// Unless public, the response may only be cached by the client.
+// Requests with a matching If-None-Match header receive 304 Not Modified.
+func writeJSON(w http.ResponseWriter, r *http.Request, body []byte, maxAge time.Duration, public bool) {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	hash := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+
+	visibility := "private"
+	if public {
+		visibility = "public"
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int(maxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
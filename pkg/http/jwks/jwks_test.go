@@ -0,0 +1,140 @@
+package jwks
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/romnn/go-service/pkg/auth"
+)
+
+type testClaims struct {
+	jwt.RegisteredClaims
+}
+
+func (claims *testClaims) GetRegisteredClaims() *jwt.RegisteredClaims {
+	return &claims.RegisteredClaims
+}
+
+type test struct {
+	authenticator *auth.Authenticator
+	server        *httptest.Server
+}
+
+func (test *test) setup(t *testing.T) *test {
+	t.Parallel()
+
+	test.authenticator = &auth.Authenticator{
+		ExpiresAfter: 100 * time.Second,
+		Issuer:       "https://issuer.example.org",
+		Audience:     "mock-audience",
+	}
+	config := auth.KeyConfig{Generate: true, Algorithm: "ES256"}
+	if err := test.authenticator.SetupKeys(&config); err != nil {
+		t.Fatalf("failed to setup keys: %v", err)
+	}
+	test.server = httptest.NewServer(NewServeMux(test.authenticator))
+	return test
+}
+
+func (test *test) teardown() {
+	test.server.Close()
+}
+
+func TestJwksHandler(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	// tokens can be verified using the published JWK set
+	verifier := &auth.Authenticator{}
+	if err := verifier.SetupKeys(&auth.KeyConfig{JwksURL: test.server.URL + JwksPath}); err != nil {
+		t.Fatalf("failed to setup verification keys: %v", err)
+	}
+	defer verifier.Close()
+	token, err := test.authenticator.SignJwtClaims(&testClaims{})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if valid, _, err := verifier.Validate(token, &testClaims{}); err != nil || !valid {
+		t.Errorf("expected token to be valid: %v", err)
+	}
+
+	res, err := http.Get(test.server.URL + JwksPath)
+	if err != nil {
+		t.Fatalf("failed to get JWK set: %v", err)
+	}
+	res.Body.Close()
+	if cacheControl := res.Header.Get("Cache-Control"); cacheControl != "public, max-age=900" {
+		t.Errorf("unexpected Cache-Control header %q", cacheControl)
+	}
+
+	// unchanged JWK sets are not sent again
+	req, _ := http.NewRequest(http.MethodGet, test.server.URL+JwksPath, nil)
+	req.Header.Set("If-None-Match", res.Header.Get("ETag"))
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to get JWK set: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("expected status %d but got %d", http.StatusNotModified, res.StatusCode)
+	}
+}
+
+func TestDiscoveryHandler(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	res, err := http.Get(test.server.URL + OpenIDConfigurationPath)
+	if err != nil {
+		t.Fatalf("failed to get OpenID configuration: %v", err)
+	}
+	defer res.Body.Close()
+
+	var config OpenIDConfiguration
+	if err := json.NewDecoder(res.Body).Decode(&config); err != nil {
+		t.Fatalf("failed to decode OpenID configuration: %v", err)
+	}
+	if config.Issuer != test.authenticator.Issuer {
+		t.Errorf("expected issuer %q but got %q", test.authenticator.Issuer, config.Issuer)
+	}
+	if expected := test.server.URL + JwksPath; config.JwksURI != expected {
+		t.Errorf("expected JWKS URI %q but got %q", expected, config.JwksURI)
+	}
+	if expected := []string{"ES256"}; !reflect.DeepEqual(config.IDTokenSigningAlgValuesSupported, expected) {
+		t.Errorf("expected signing algorithms %v but got %v", expected, config.IDTokenSigningAlgValuesSupported)
+	}
+	// derived from the request headers, which shared caches do not distinguish
+	if cacheControl := res.Header.Get("Cache-Control"); !strings.HasPrefix(cacheControl, "private") {
+		t.Errorf("expected private caching but got %q", cacheControl)
+	}
+}
+
+func TestDiscoveryHandlerWithJwksURI(t *testing.T) {
+	test := new(test).setup(t)
+	defer test.teardown()
+
+	jwksURI := test.authenticator.Issuer + JwksPath
+	handler := &DiscoveryHandler{Authenticator: test.authenticator, JwksURI: jwksURI}
+	req := httptest.NewRequest(http.MethodGet, OpenIDConfigurationPath, nil)
+	req.Host = "attacker.example.org"
+	req.Header.Set("X-Forwarded-Proto", "http")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var config OpenIDConfiguration
+	if err := json.NewDecoder(rec.Body).Decode(&config); err != nil {
+		t.Fatalf("failed to decode OpenID configuration: %v", err)
+	}
+	if config.JwksURI != jwksURI {
+		t.Errorf("expected JWKS URI %q but got %q", jwksURI, config.JwksURI)
+	}
+	if cacheControl := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cacheControl, "public") {
+		t.Errorf("expected public caching but got %q", cacheControl)
+	}
+}
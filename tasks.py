@@ -73,10 +73,26 @@ def compile_protos(c):
     import shutil
     from pprint import pprint
 
+    proto_dir = ROOT_DIR / "proto"
+    options = [
+        proto_dir / "go_service" / "auth.proto",
+    ]
+    for option in options:
+        print(f"compiling {option.relative_to(ROOT_DIR)}")
+        cmd = [
+            "protoc",
+            f"--proto_path={proto_dir}",
+            f"--go_out={ROOT_DIR}",
+            f"--go_opt=module={PKG}",
+            str(option),
+        ]
+        c.run(" ".join(cmd))
+
     services = [
         ROOT_DIR / "examples" / "grpc" / "grpc.proto",
         ROOT_DIR / "examples" / "auth" / "auth.proto",
         ROOT_DIR / "examples" / "reflect" / "reflect.proto",
+        ROOT_DIR / "examples" / "policy" / "policy.proto",
     ]
     for service in services:
         proto_path = service.parent
@@ -97,6 +113,7 @@ def compile_protos(c):
         cmd = [
             "protoc",
             f"--proto_path={proto_path}",
+            f"--proto_path={proto_dir}",
             f"--go_opt=M{package}",
             f"--go-grpc_opt=M{package}",
             f"--go_out={out_dir}",